@@ -21,6 +21,7 @@ import (
 	"github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
 	apiconversion "k8s.io/apimachinery/pkg/conversion"
 	"net/url"
+	utilconversion "sigs.k8s.io/cluster-api/util/conversion"
 	"sigs.k8s.io/controller-runtime/pkg/conversion"
 	"strconv"
 )
@@ -32,7 +33,19 @@ const (
 
 func (src *BareMetalCluster) ConvertTo(dstRaw conversion.Hub) error {
 	dst := dstRaw.(*v1alpha3.BareMetalCluster)
-	return Convert_v1alpha2_BareMetalCluster_To_v1alpha3_BareMetalCluster(src, dst, nil)
+	if err := Convert_v1alpha2_BareMetalCluster_To_v1alpha3_BareMetalCluster(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Manually restore data from annotations
+	restored := &v1alpha3.BareMetalCluster{}
+	if ok, err := utilconversion.UnmarshalData(src, restored); err != nil || !ok {
+		return err
+	}
+	dst.Spec.FailureDomainLabel = restored.Spec.FailureDomainLabel
+	dst.Status.FailureDomains = restored.Status.FailureDomains
+
+	return nil
 }
 
 func (dst *BareMetalCluster) ConvertFrom(srcRaw conversion.Hub) error {
@@ -48,7 +61,9 @@ func (dst *BareMetalCluster) ConvertFrom(srcRaw conversion.Hub) error {
 			Port: src.Spec.ControlPlaneEndpoint.Port,
 		},
 	}
-	return nil
+
+	// Preserve Hub data on down-conversion
+	return utilconversion.MarshalData(src, dst)
 }
 
 func (src *BareMetalClusterList) ConvertTo(dstRaw conversion.Hub) error {
@@ -65,12 +80,34 @@ func (dst *BareMetalClusterList) ConvertFrom(srcRaw conversion.Hub) error {
 
 func (src *BareMetalMachine) ConvertTo(dstRaw conversion.Hub) error {
 	dst := dstRaw.(*v1alpha3.BareMetalMachine)
-	return Convert_v1alpha2_BareMetalMachine_To_v1alpha3_BareMetalMachine(src, dst, nil)
+	if err := Convert_v1alpha2_BareMetalMachine_To_v1alpha3_BareMetalMachine(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Manually restore data from annotations
+	restored := &v1alpha3.BareMetalMachine{}
+	if ok, err := utilconversion.UnmarshalData(src, restored); err != nil || !ok {
+		return err
+	}
+	restoreBareMetalMachineSpec(&restored.Spec, &dst.Spec)
+	dst.Status.HostSelection = restored.Status.HostSelection
+	dst.Status.ImageUpgrade = restored.Status.ImageUpgrade
+	dst.Status.UserDataDrifted = restored.Status.UserDataDrifted
+	dst.Status.NodeDrain = restored.Status.NodeDrain
+	dst.Status.Deprovisioning = restored.Status.Deprovisioning
+	dst.Status.PowerAction = restored.Status.PowerAction
+
+	return nil
 }
 
 func (dst *BareMetalMachine) ConvertFrom(srcRaw conversion.Hub) error {
 	src := srcRaw.(*v1alpha3.BareMetalMachine)
-	return Convert_v1alpha3_BareMetalMachine_To_v1alpha2_BareMetalMachine(src, dst, nil)
+	if err := Convert_v1alpha3_BareMetalMachine_To_v1alpha2_BareMetalMachine(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Preserve Hub data on down-conversion
+	return utilconversion.MarshalData(src, dst)
 }
 
 func (src *BareMetalMachineList) ConvertTo(dstRaw conversion.Hub) error {
@@ -87,12 +124,28 @@ func (dst *BareMetalMachineList) ConvertFrom(srcRaw conversion.Hub) error {
 
 func (src *BareMetalMachineTemplate) ConvertTo(dstRaw conversion.Hub) error {
 	dst := dstRaw.(*v1alpha3.BareMetalMachineTemplate)
-	return Convert_v1alpha2_BareMetalMachineTemplate_To_v1alpha3_BareMetalMachineTemplate(src, dst, nil)
+	if err := Convert_v1alpha2_BareMetalMachineTemplate_To_v1alpha3_BareMetalMachineTemplate(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Manually restore data from annotations
+	restored := &v1alpha3.BareMetalMachineTemplate{}
+	if ok, err := utilconversion.UnmarshalData(src, restored); err != nil || !ok {
+		return err
+	}
+	restoreBareMetalMachineSpec(&restored.Spec.Template.Spec, &dst.Spec.Template.Spec)
+
+	return nil
 }
 
 func (dst *BareMetalMachineTemplate) ConvertFrom(srcRaw conversion.Hub) error {
 	src := srcRaw.(*v1alpha3.BareMetalMachineTemplate)
-	return Convert_v1alpha3_BareMetalMachineTemplate_To_v1alpha2_BareMetalMachineTemplate(src, dst, nil)
+	if err := Convert_v1alpha3_BareMetalMachineTemplate_To_v1alpha2_BareMetalMachineTemplate(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Preserve Hub data on down-conversion
+	return utilconversion.MarshalData(src, dst)
 }
 
 func (src *BareMetalMachineTemplateList) ConvertTo(dstRaw conversion.Hub) error {
@@ -194,3 +247,24 @@ func Convert_v1alpha3_BareMetalMachineStatus_To_v1alpha2_BareMetalMachineStatus(
 
 	return nil
 }
+
+func Convert_v1alpha3_BareMetalMachineSpec_To_v1alpha2_BareMetalMachineSpec(in *v1alpha3.BareMetalMachineSpec, out *BareMetalMachineSpec, s apiconversion.Scope) error {
+	// The fields that only exist in v1alpha3 are restored from the
+	// annotations, see restoreBareMetalMachineSpec
+	return autoConvert_v1alpha3_BareMetalMachineSpec_To_v1alpha2_BareMetalMachineSpec(in, out, s)
+}
+
+// restoreBareMetalMachineSpec copies the fields that do not exist in
+// v1alpha2 from the spec restored from the annotations
+func restoreBareMetalMachineSpec(restored *v1alpha3.BareMetalMachineSpec, dst *v1alpha3.BareMetalMachineSpec) {
+	dst.HostSelectionStrategy = restored.HostSelectionStrategy
+	dst.HardwareRequirements = restored.HardwareRequirements
+	dst.HostNamespaces = restored.HostNamespaces
+	dst.HostRef = restored.HostRef
+	dst.ReuseLastHost = restored.ReuseLastHost
+	dst.ImageUpgradePolicy = restored.ImageUpgradePolicy
+	dst.BootstrapSecretKey = restored.BootstrapSecretKey
+	dst.ExtraUserData = restored.ExtraUserData
+	dst.CompressUserData = restored.CompressUserData
+	dst.NodeDrain = restored.NodeDrain
+}
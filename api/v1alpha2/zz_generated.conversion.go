@@ -95,11 +95,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha3.BareMetalMachineSpec)(nil), (*BareMetalMachineSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha3_BareMetalMachineSpec_To_v1alpha2_BareMetalMachineSpec(a.(*v1alpha3.BareMetalMachineSpec), b.(*BareMetalMachineSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*BareMetalMachineTemplate)(nil), (*v1alpha3.BareMetalMachineTemplate)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha2_BareMetalMachineTemplate_To_v1alpha3_BareMetalMachineTemplate(a.(*BareMetalMachineTemplate), b.(*v1alpha3.BareMetalMachineTemplate), scope)
 	}); err != nil {
@@ -195,6 +190,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1alpha3.BareMetalMachineSpec)(nil), (*BareMetalMachineSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha3_BareMetalMachineSpec_To_v1alpha2_BareMetalMachineSpec(a.(*v1alpha3.BareMetalMachineSpec), b.(*BareMetalMachineSpec), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1alpha3.BareMetalMachineStatus)(nil), (*BareMetalMachineStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha3_BareMetalMachineStatus_To_v1alpha2_BareMetalMachineStatus(a.(*v1alpha3.BareMetalMachineStatus), b.(*BareMetalMachineStatus), scope)
 	}); err != nil {
@@ -308,6 +308,7 @@ func autoConvert_v1alpha2_BareMetalClusterSpec_To_v1alpha3_BareMetalClusterSpec(
 func autoConvert_v1alpha3_BareMetalClusterSpec_To_v1alpha2_BareMetalClusterSpec(in *v1alpha3.BareMetalClusterSpec, out *BareMetalClusterSpec, s conversion.Scope) error {
 	// WARNING: in.ControlPlaneEndpoint requires manual conversion: does not exist in peer-type
 	out.NoCloudProvider = in.NoCloudProvider
+	// WARNING: in.FailureDomainLabel requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -325,6 +326,7 @@ func autoConvert_v1alpha3_BareMetalClusterStatus_To_v1alpha2_BareMetalClusterSta
 	// WARNING: in.FailureReason requires manual conversion: does not exist in peer-type
 	// WARNING: in.FailureMessage requires manual conversion: does not exist in peer-type
 	out.Ready = in.Ready
+	// WARNING: in.FailureDomains requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -425,17 +427,22 @@ func autoConvert_v1alpha3_BareMetalMachineSpec_To_v1alpha2_BareMetalMachineSpec(
 		return err
 	}
 	out.UserData = (*corev1.SecretReference)(unsafe.Pointer(in.UserData))
+	// WARNING: in.BootstrapSecretKey requires manual conversion: does not exist in peer-type
+	// WARNING: in.ExtraUserData requires manual conversion: does not exist in peer-type
+	// WARNING: in.CompressUserData requires manual conversion: does not exist in peer-type
 	if err := Convert_v1alpha3_HostSelector_To_v1alpha2_HostSelector(&in.HostSelector, &out.HostSelector, s); err != nil {
 		return err
 	}
+	// WARNING: in.HardwareRequirements requires manual conversion: does not exist in peer-type
+	// WARNING: in.HostSelectionStrategy requires manual conversion: does not exist in peer-type
+	// WARNING: in.HostNamespaces requires manual conversion: does not exist in peer-type
+	// WARNING: in.HostRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReuseLastHost requires manual conversion: does not exist in peer-type
+	// WARNING: in.ImageUpgradePolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.NodeDrain requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1alpha3_BareMetalMachineSpec_To_v1alpha2_BareMetalMachineSpec is an autogenerated conversion function.
-func Convert_v1alpha3_BareMetalMachineSpec_To_v1alpha2_BareMetalMachineSpec(in *v1alpha3.BareMetalMachineSpec, out *BareMetalMachineSpec, s conversion.Scope) error {
-	return autoConvert_v1alpha3_BareMetalMachineSpec_To_v1alpha2_BareMetalMachineSpec(in, out, s)
-}
-
 func autoConvert_v1alpha2_BareMetalMachineStatus_To_v1alpha3_BareMetalMachineStatus(in *BareMetalMachineStatus, out *v1alpha3.BareMetalMachineStatus, s conversion.Scope) error {
 	out.LastUpdated = (*v1.Time)(unsafe.Pointer(in.LastUpdated))
 	// WARNING: in.ErrorReason requires manual conversion: does not exist in peer-type
@@ -453,6 +460,12 @@ func autoConvert_v1alpha3_BareMetalMachineStatus_To_v1alpha2_BareMetalMachineSta
 	out.Addresses = *(*apiv1alpha2.MachineAddresses)(unsafe.Pointer(&in.Addresses))
 	out.Phase = in.Phase
 	out.Ready = in.Ready
+	// WARNING: in.HostSelection requires manual conversion: does not exist in peer-type
+	// WARNING: in.ImageUpgrade requires manual conversion: does not exist in peer-type
+	// WARNING: in.UserDataDrifted requires manual conversion: does not exist in peer-type
+	// WARNING: in.NodeDrain requires manual conversion: does not exist in peer-type
+	// WARNING: in.Deprovisioning requires manual conversion: does not exist in peer-type
+	// WARNING: in.PowerAction requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -484,7 +497,17 @@ func Convert_v1alpha3_BareMetalMachineTemplate_To_v1alpha2_BareMetalMachineTempl
 
 func autoConvert_v1alpha2_BareMetalMachineTemplateList_To_v1alpha3_BareMetalMachineTemplateList(in *BareMetalMachineTemplateList, out *v1alpha3.BareMetalMachineTemplateList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]v1alpha3.BareMetalMachineTemplate)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]v1alpha3.BareMetalMachineTemplate, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha2_BareMetalMachineTemplate_To_v1alpha3_BareMetalMachineTemplate(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -495,7 +518,17 @@ func Convert_v1alpha2_BareMetalMachineTemplateList_To_v1alpha3_BareMetalMachineT
 
 func autoConvert_v1alpha3_BareMetalMachineTemplateList_To_v1alpha2_BareMetalMachineTemplateList(in *v1alpha3.BareMetalMachineTemplateList, out *BareMetalMachineTemplateList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]BareMetalMachineTemplate)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BareMetalMachineTemplate, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha3_BareMetalMachineTemplate_To_v1alpha2_BareMetalMachineTemplate(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
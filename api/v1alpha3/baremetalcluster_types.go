@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
 	capierrors "sigs.k8s.io/cluster-api/errors"
 )
 
@@ -34,6 +35,13 @@ type BareMetalClusterSpec struct {
 	// ControlPlaneEndpoint represents the endpoint used to communicate with the control plane.
 	ControlPlaneEndpoint APIEndpoint `json:"controlPlaneEndpoint"`
 	NoCloudProvider      bool        `json:"noCloudProvider,omitempty"`
+
+	// FailureDomainLabel is the key of the label on the BareMetalHosts whose
+	// value is the failure domain of the host, for example the rack. If set,
+	// the failure domains found on the hosts are published in the status and
+	// the machines are only created on hosts of their failure domain.
+	// +optional
+	FailureDomainLabel string `json:"failureDomainLabel,omitempty"`
 }
 
 // IsValid returns an error if the object is not valid, otherwise nil. The
@@ -76,6 +84,11 @@ type BareMetalClusterStatus struct {
 	// steps need to be performed. Required by Cluster API. Set to True by the
 	// BaremetalCluster controller after creation.
 	Ready bool `json:"ready"`
+
+	// FailureDomains is the list of failure domains found on the
+	// BareMetalHosts with the FailureDomainLabel.
+	// +optional
+	FailureDomains capi.FailureDomains `json:"failureDomains,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -76,6 +76,46 @@ func (c *BareMetalMachineTemplate) validate() error {
 
 	}
 
+	allErrs = append(allErrs, validateImageTemplates(
+		field.NewPath("spec", "Template", "Spec", "Image"),
+		c.Spec.Template.Spec.Image,
+	)...)
+
+	allErrs = append(allErrs, validateHardwareRequirements(
+		field.NewPath("spec", "Template", "Spec", "HardwareRequirements"),
+		c.Spec.Template.Spec.HardwareRequirements,
+	)...)
+
+	allErrs = append(allErrs, validateHostNamespaces(
+		field.NewPath("spec", "Template", "Spec", "HostNamespaces"),
+		c.Spec.Template.Spec.HostNamespaces,
+	)...)
+
+	allErrs = append(allErrs, validateBootstrapSecretKey(
+		field.NewPath("spec", "Template", "Spec", "BootstrapSecretKey"),
+		c.Spec.Template.Spec.BootstrapSecretKey,
+	)...)
+
+	allErrs = append(allErrs, validateExtraUserData(
+		field.NewPath("spec", "Template", "Spec", "ExtraUserData"),
+		c.Spec.Template.Spec.ExtraUserData,
+	)...)
+
+	allErrs = append(allErrs, validateNodeDrain(
+		field.NewPath("spec", "Template", "Spec", "NodeDrain"),
+		c.Spec.Template.Spec.NodeDrain,
+	)...)
+
+	if c.Spec.Template.Spec.HostRef != nil {
+		allErrs = append(
+			allErrs,
+			field.Forbidden(
+				field.NewPath("spec", "Template", "Spec", "HostRef"),
+				"can not be set in a template, all the machines would be pinned to the same host",
+			),
+		)
+	}
+
 	if len(allErrs) == 0 {
 		return nil
 	}
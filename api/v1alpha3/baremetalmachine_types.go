@@ -30,6 +30,57 @@ const (
 	MachineFinalizer = "baremetalmachine.infrastructure.cluster.x-k8s.io"
 )
 
+// HostSelectionStrategy is the strategy used to pick a BareMetalHost among
+// the hosts available for a BareMetalMachine.
+// +kubebuilder:validation:Enum=random;leastRecentlyUsed;bestFit;packByRack;spreadByRack
+type HostSelectionStrategy string
+
+const (
+	// HostSelectionRandom picks a random host among the available ones. This
+	// is the default.
+	HostSelectionRandom HostSelectionStrategy = "random"
+	// HostSelectionLeastRecentlyUsed picks the host that has been idle for the
+	// longest time.
+	HostSelectionLeastRecentlyUsed HostSelectionStrategy = "leastRecentlyUsed"
+	// HostSelectionBestFit picks the host with the smallest hardware, keeping
+	// the bigger hosts for the machines that need them.
+	HostSelectionBestFit HostSelectionStrategy = "bestFit"
+	// HostSelectionPackByRack picks a host in the rack that already holds the
+	// most hosts of the cluster.
+	HostSelectionPackByRack HostSelectionStrategy = "packByRack"
+	// HostSelectionSpreadByRack picks a host in the rack that holds the fewest
+	// hosts of the cluster.
+	HostSelectionSpreadByRack HostSelectionStrategy = "spreadByRack"
+)
+
+// ImageUpgradePolicy is the policy applied when the image of a
+// BareMetalMachine changes after its BareMetalHost was provisioned.
+// +kubebuilder:validation:Enum=None;Reprovision
+type ImageUpgradePolicy string
+
+const (
+	// ImageUpgradePolicyNone ignores the image changes. This is the default.
+	ImageUpgradePolicyNone ImageUpgradePolicy = "None"
+	// ImageUpgradePolicyReprovision deprovisions the BareMetalHost and
+	// provisions it again with the new image and the same user data.
+	ImageUpgradePolicyReprovision ImageUpgradePolicy = "Reprovision"
+)
+
+// ImageUpgradePhase is the phase of an image upgrade.
+type ImageUpgradePhase string
+
+const (
+	// ImageUpgradeDeprovisioning means that the BareMetalHost is being
+	// deprovisioned.
+	ImageUpgradeDeprovisioning ImageUpgradePhase = "Deprovisioning"
+	// ImageUpgradeProvisioning means that the BareMetalHost is being
+	// provisioned with the new image.
+	ImageUpgradeProvisioning ImageUpgradePhase = "Provisioning"
+	// ImageUpgradeCompleted means that the BareMetalHost was provisioned
+	// with the new image.
+	ImageUpgradeCompleted ImageUpgradePhase = "Completed"
+)
+
 // BareMetalMachineSpec defines the desired state of BareMetalMachine
 type BareMetalMachineSpec struct {
 	// ProviderID will be the baremetal machine in ProviderID format
@@ -45,10 +96,82 @@ type BareMetalMachineSpec struct {
 	// namespace if not specified.
 	UserData *corev1.SecretReference `json:"userData,omitempty"`
 
+	// BootstrapSecretKey is the key of the bootstrap data in the Secret
+	// referenced by the Machine. Defaults to value, the key used by the
+	// cluster-api bootstrap providers.
+	// +optional
+	BootstrapSecretKey string `json:"bootstrapSecretKey,omitempty"`
+
+	// ExtraUserData references keys of Secrets, in the namespace of the
+	// BareMetalMachine, holding user data merged with the bootstrap data of
+	// the Machine, e.g. cloud-configs adding SSH keys or CA bundles.
+	// +optional
+	ExtraUserData []corev1.SecretKeySelector `json:"extraUserData,omitempty"`
+
+	// CompressUserData gzips the user data written for the BareMetalHost,
+	// which cloud-init decompresses, to fit in size-limited config drives.
+	// Ignition bootstrap data can not be compressed.
+	// +optional
+	CompressUserData bool `json:"compressUserData,omitempty"`
+
 	// HostSelector specifies matching criteria for labels on BareMetalHosts.
 	// This is used to limit the set of BareMetalHost objects considered for
 	// claiming for a BaremetalMachine.
 	HostSelector HostSelector `json:"hostSelector,omitempty"`
+
+	// HardwareRequirements specifies the minimal hardware of the
+	// BareMetalHosts that can be claimed for a BareMetalMachine.
+	// +optional
+	HardwareRequirements *HardwareRequirements `json:"hardwareRequirements,omitempty"`
+
+	// HostSelectionStrategy is the strategy used to choose a BareMetalHost
+	// among the ones matching the HostSelector. Defaults to random.
+	// +optional
+	HostSelectionStrategy HostSelectionStrategy `json:"hostSelectionStrategy,omitempty"`
+
+	// HostNamespaces is the list of namespaces in which to look for
+	// BareMetalHosts. Defaults to the namespace of the Machine. Any other
+	// namespace must be allowed in the controller configuration.
+	// +optional
+	HostNamespaces []string `json:"hostNamespaces,omitempty"`
+
+	// HostRef pins the BareMetalMachine to a specific BareMetalHost. Only
+	// the name and namespace are used, the namespace defaults to the one of
+	// the Machine. The host must be available and match the HostSelector
+	// and HardwareRequirements. It can not be set in a
+	// BareMetalMachineTemplate.
+	// +optional
+	HostRef *corev1.ObjectReference `json:"hostRef,omitempty"`
+
+	// ReuseLastHost makes the BareMetalMachine prefer, among the available
+	// BareMetalHosts, the one last used by a Machine of the same name or,
+	// failing that, by a Machine of the same MachineSet, so that local data
+	// and cached images are reused. The HostSelectionStrategy is used when
+	// there is no such host.
+	// +optional
+	ReuseLastHost bool `json:"reuseLastHost,omitempty"`
+
+	// ImageUpgradePolicy is the policy applied when the Image changes after
+	// the BareMetalHost was provisioned. Defaults to None, ignoring the
+	// change.
+	// +optional
+	ImageUpgradePolicy ImageUpgradePolicy `json:"imageUpgradePolicy,omitempty"`
+
+	// NodeDrain, when set, makes the deletion of the BareMetalMachine cordon
+	// the Node of the BareMetalHost and evict its pods, honouring the
+	// PodDisruptionBudgets, before the host is deprovisioned.
+	// +optional
+	NodeDrain *NodeDrainPolicy `json:"nodeDrain,omitempty"`
+}
+
+// NodeDrainPolicy configures the drain of the Node before the BareMetalHost
+// is deprovisioned.
+type NodeDrainPolicy struct {
+	// Timeout is the maximal duration of the drain, after which the host is
+	// deprovisioned even if pods are left, e.g. because of a
+	// PodDisruptionBudget that can not be satisfied. Defaults to 20 minutes.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
 }
 
 // IsValid returns an error if the object is not valid, otherwise nil. The
@@ -128,6 +251,160 @@ type BareMetalMachineStatus struct {
 	// it, under what circumstances the value changes, etc."
 	// +optional
 	Ready bool `json:"ready"`
+
+	// HostSelection records how the BareMetalHost was chosen for this
+	// BareMetalMachine, or why none could be chosen.
+	// +optional
+	HostSelection *HostSelectionStatus `json:"hostSelection,omitempty"`
+
+	// ImageUpgrade records the progress of the last image upgrade, see
+	// ImageUpgradePolicy.
+	// +optional
+	ImageUpgrade *ImageUpgradeStatus `json:"imageUpgrade,omitempty"`
+
+	// UserDataDrifted is true when the bootstrap data of the Machine changed
+	// after the BareMetalHost started provisioning, so the host does not run
+	// the latest user data. The Machine must be re-created to use it.
+	// +optional
+	UserDataDrifted bool `json:"userDataDrifted,omitempty"`
+
+	// NodeDrain records the progress of the drain of the Node before the
+	// BareMetalHost is deprovisioned, see NodeDrain in the spec.
+	// +optional
+	NodeDrain *NodeDrainStatus `json:"nodeDrain,omitempty"`
+
+	// Deprovisioning records the progress of the deprovisioning of the
+	// BareMetalHost when the BareMetalMachine is deleted.
+	// +optional
+	Deprovisioning *DeprovisioningStatus `json:"deprovisioning,omitempty"`
+
+	// PowerAction records the progress of the last power action requested
+	// through the annotations of the BareMetalMachine.
+	// +optional
+	PowerAction *PowerActionStatus `json:"powerAction,omitempty"`
+}
+
+// PowerAction is a power action applied to the BareMetalHost of a
+// BareMetalMachine.
+type PowerAction string
+
+const (
+	// PowerActionReboot powers the BareMetalHost off and on again.
+	PowerActionReboot PowerAction = "Reboot"
+	// PowerActionPowerOff keeps the BareMetalHost powered off.
+	PowerActionPowerOff PowerAction = "PowerOff"
+)
+
+// PowerActionStatus records the progress of a power action.
+type PowerActionStatus struct {
+	// Action is the requested power action.
+	Action PowerAction `json:"action"`
+
+	// RequestedAt is the time the request was handled.
+	RequestedAt metav1.Time `json:"requestedAt"`
+
+	// PoweredOffAt is the time the BareMetalHost was found powered off.
+	// +optional
+	PoweredOffAt *metav1.Time `json:"poweredOffAt,omitempty"`
+
+	// CompletedAt is the time the action completed, that is when the
+	// BareMetalHost was found powered off for a PowerOff, or powered on
+	// again for a Reboot.
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// Refused is the reason why the action was refused, if it was.
+	// +optional
+	Refused string `json:"refused,omitempty"`
+
+	// Pending is the reason why the action is not applied yet, if it is
+	// not, for example because the BareMetalHost is not provisioned.
+	// +optional
+	Pending string `json:"pending,omitempty"`
+}
+
+// DeprovisioningStatus records the progress of the deprovisioning of a
+// BareMetalHost.
+type DeprovisioningStatus struct {
+	// StartedAt is the time the deprovisioning started.
+	StartedAt metav1.Time `json:"startedAt"`
+
+	// TimedOut is true if the BareMetalHost was still not deprovisioned
+	// when the deprovisioning timeout expired.
+	// +optional
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
+// NodeDrainStatus records the progress of the drain of a Node.
+type NodeDrainStatus struct {
+	// Node is the name of the drained Node.
+	// +optional
+	Node string `json:"node,omitempty"`
+
+	// StartedAt is the time the drain started.
+	StartedAt metav1.Time `json:"startedAt"`
+
+	// CompletedAt is the time the drain completed, or timed out.
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// TimedOut is true if pods were left on the Node when the drain timed
+	// out.
+	// +optional
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
+// ImageUpgradeStatus records the progress of an image upgrade.
+type ImageUpgradeStatus struct {
+	// Phase is the phase of the upgrade.
+	Phase ImageUpgradePhase `json:"phase"`
+
+	// Image is the URL of the image the BareMetalHost is upgraded to.
+	Image string `json:"image"`
+
+	// StartedAt is the time the upgrade started.
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// CompletedAt is the time the upgrade completed.
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// HostSelectionStatus records the outcome of the host selection.
+type HostSelectionStatus struct {
+	// Strategy is the host selection strategy that was used.
+	Strategy HostSelectionStrategy `json:"strategy"`
+
+	// Score is the score given to the chosen host by the strategy. The
+	// meaning of the score depends on the strategy.
+	Score int64 `json:"score"`
+
+	// Selector is the label selector built from the HostSelector.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// Reused is true if the chosen host was last used by a Machine of the
+	// same name or MachineSet, see ReuseLastHost.
+	// +optional
+	Reused bool `json:"reused,omitempty"`
+
+	// Considered is the number of BareMetalHosts considered.
+	Considered int `json:"considered"`
+
+	// NotAvailable is the number of hosts rejected because they were not
+	// available, being consumed, in error or being deleted, including the
+	// hosts claimed concurrently by other BareMetalMachines.
+	NotAvailable int `json:"notAvailable"`
+
+	// RejectedBySelector is the number of available hosts rejected because
+	// they did not match the HostSelector.
+	RejectedBySelector int `json:"rejectedBySelector"`
+
+	// RejectedByFilters is the number of available hosts matching the
+	// HostSelector rejected by the other filters, such as the
+	// HardwareRequirements or the failure domain.
+	RejectedByFilters int `json:"rejectedByFilters"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
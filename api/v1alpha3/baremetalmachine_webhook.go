@@ -14,8 +14,10 @@ limitations under the License.
 package v1alpha3
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -76,8 +78,173 @@ func (c *BareMetalMachine) validate() error {
 
 	}
 
+	allErrs = append(allErrs, validateImageTemplates(
+		field.NewPath("spec", "Image"),
+		c.Spec.Image,
+	)...)
+
+	allErrs = append(allErrs, validateHardwareRequirements(
+		field.NewPath("spec", "HardwareRequirements"),
+		c.Spec.HardwareRequirements,
+	)...)
+
+	allErrs = append(allErrs, validateHostNamespaces(
+		field.NewPath("spec", "HostNamespaces"),
+		c.Spec.HostNamespaces,
+	)...)
+
+	allErrs = append(allErrs, validateBootstrapSecretKey(
+		field.NewPath("spec", "BootstrapSecretKey"),
+		c.Spec.BootstrapSecretKey,
+	)...)
+
+	allErrs = append(allErrs, validateExtraUserData(
+		field.NewPath("spec", "ExtraUserData"),
+		c.Spec.ExtraUserData,
+	)...)
+
+	allErrs = append(allErrs, validateNodeDrain(
+		field.NewPath("spec", "NodeDrain"),
+		c.Spec.NodeDrain,
+	)...)
+
+	if c.Spec.HostRef != nil && len(c.Spec.HostRef.Name) == 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				field.NewPath("spec", "HostRef", "Name"),
+				c.Spec.HostRef.Name,
+				"is required",
+			),
+		)
+	}
+
 	if len(allErrs) == 0 {
 		return nil
 	}
 	return apierrors.NewInvalid(GroupVersion.WithKind("BareMetalMachine").GroupKind(), c.Name, allErrs)
 }
+
+// validateImageTemplates checks the templates of the URL and the checksum
+func validateImageTemplates(path *field.Path, image Image) field.ErrorList {
+	var allErrs field.ErrorList
+	if err := validateImageTemplate(image.URL); err != nil {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				path.Child("URL"),
+				image.URL,
+				"invalid template: "+err.Error(),
+			),
+		)
+	}
+	if err := validateImageTemplate(image.Checksum); err != nil {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				path.Child("Checksum"),
+				image.Checksum,
+				"invalid template: "+err.Error(),
+			),
+		)
+	}
+	return allErrs
+}
+
+// validateHardwareRequirements checks that none of the minimal values of the
+// hardware requirements is negative
+func validateHardwareRequirements(path *field.Path, requirements *HardwareRequirements) field.ErrorList {
+	var allErrs field.ErrorList
+	if requirements == nil {
+		return allErrs
+	}
+
+	minimums := []struct {
+		name  string
+		value int
+	}{
+		{"MinCPUCount", requirements.MinCPUCount},
+		{"MinRAMMebibytes", requirements.MinRAMMebibytes},
+		{"MinDiskSizeGibibytes", requirements.MinDiskSizeGibibytes},
+		{"MinNICCount", requirements.MinNICCount},
+	}
+	for _, minimum := range minimums {
+		if minimum.value < 0 {
+			allErrs = append(
+				allErrs,
+				field.Invalid(
+					path.Child(minimum.name),
+					minimum.value,
+					"must not be negative",
+				),
+			)
+		}
+	}
+	return allErrs
+}
+
+// validateBootstrapSecretKey checks that the key is a valid Secret key
+func validateBootstrapSecretKey(path *field.Path, key string) field.ErrorList {
+	var allErrs field.ErrorList
+	if key == "" {
+		return allErrs
+	}
+	for _, msg := range validation.IsConfigMapKey(key) {
+		allErrs = append(allErrs, field.Invalid(path, key, msg))
+	}
+	return allErrs
+}
+
+// validateExtraUserData checks that the extra user data references a key of a
+// Secret
+func validateExtraUserData(path *field.Path, extraUserData []corev1.SecretKeySelector) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, ref := range extraUserData {
+		if ref.Name == "" {
+			allErrs = append(
+				allErrs,
+				field.Required(path.Index(i).Child("Name"), "must be set"),
+			)
+		}
+		if ref.Key == "" {
+			allErrs = append(
+				allErrs,
+				field.Required(path.Index(i).Child("Key"), "must be set"),
+			)
+		}
+	}
+	return allErrs
+}
+
+// validateNodeDrain checks that the drain timeout is positive
+func validateNodeDrain(path *field.Path, nodeDrain *NodeDrainPolicy) field.ErrorList {
+	var allErrs field.ErrorList
+	if nodeDrain != nil && nodeDrain.Timeout != nil && nodeDrain.Timeout.Duration <= 0 {
+		allErrs = append(
+			allErrs,
+			field.Invalid(
+				path.Child("Timeout"),
+				nodeDrain.Timeout.Duration.String(),
+				"must be positive",
+			),
+		)
+	}
+	return allErrs
+}
+
+func validateHostNamespaces(path *field.Path, namespaces []string) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, namespace := range namespaces {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			allErrs = append(
+				allErrs,
+				field.Invalid(
+					path.Index(i),
+					namespace,
+					msg,
+				),
+			)
+		}
+	}
+	return allErrs
+}
@@ -15,8 +15,10 @@ package v1alpha3
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -49,6 +51,69 @@ func TestBareMetalMachineValidation(t *testing.T) {
 	invalidChecksum := valid.DeepCopy()
 	invalidChecksum.Spec.Image.Checksum = ""
 
+	invalidHardwareRequirements := valid.DeepCopy()
+	invalidHardwareRequirements.Spec.HardwareRequirements = &HardwareRequirements{
+		MinCPUCount: -1,
+	}
+
+	validHardwareRequirements := valid.DeepCopy()
+	validHardwareRequirements.Spec.HardwareRequirements = &HardwareRequirements{
+		MinCPUCount:     4,
+		MinRAMMebibytes: 8192,
+		CPUArchitecture: "x86_64",
+	}
+
+	invalidHostNamespaces := valid.DeepCopy()
+	invalidHostNamespaces.Spec.HostNamespaces = []string{"Not_A_Namespace"}
+
+	validHostNamespaces := valid.DeepCopy()
+	validHostNamespaces.Spec.HostNamespaces = []string{"inventory"}
+
+	invalidBootstrapSecretKey := valid.DeepCopy()
+	invalidBootstrapSecretKey.Spec.BootstrapSecretKey = "user data"
+
+	validBootstrapSecretKey := valid.DeepCopy()
+	validBootstrapSecretKey.Spec.BootstrapSecretKey = "userData"
+
+	invalidExtraUserData := valid.DeepCopy()
+	invalidExtraUserData.Spec.ExtraUserData = []corev1.SecretKeySelector{
+		{LocalObjectReference: corev1.LocalObjectReference{Name: "ssh-keys"}},
+	}
+
+	invalidNodeDrain := valid.DeepCopy()
+	invalidNodeDrain.Spec.NodeDrain = &NodeDrainPolicy{
+		Timeout: &metav1.Duration{Duration: -time.Minute},
+	}
+
+	validNodeDrain := valid.DeepCopy()
+	validNodeDrain.Spec.NodeDrain = &NodeDrainPolicy{
+		Timeout: &metav1.Duration{Duration: 10 * time.Minute},
+	}
+
+	validExtraUserData := valid.DeepCopy()
+	validExtraUserData.Spec.ExtraUserData = []corev1.SecretKeySelector{
+		{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "ssh-keys"},
+			Key:                  "cloud-config",
+		},
+	}
+
+	invalidHostRef := valid.DeepCopy()
+	invalidHostRef.Spec.HostRef = &corev1.ObjectReference{Namespace: "inventory"}
+
+	validHostRef := valid.DeepCopy()
+	validHostRef.Spec.HostRef = &corev1.ObjectReference{Name: "host-0"}
+
+	invalidURLTemplate := valid.DeepCopy()
+	invalidURLTemplate.Spec.Image.URL = "http://172.22.0.1/images/{{ .Arch.qcow2"
+
+	invalidChecksumTemplate := valid.DeepCopy()
+	invalidChecksumTemplate.Spec.Image.Checksum = "http://172.22.0.1/images/{{ .Architecture }}.md5sum"
+
+	validTemplates := valid.DeepCopy()
+	validTemplates.Spec.Image.URL = "http://172.22.0.1/images/{{ .Labels.os }}-{{ .Arch }}.qcow2"
+	validTemplates.Spec.Image.Checksum = "{{ index .Labels \"checksum\" }}"
+
 	tests := []struct {
 		name      string
 		expectErr bool
@@ -69,6 +134,81 @@ func TestBareMetalMachineValidation(t *testing.T) {
 			expectErr: false,
 			c:         valid,
 		},
+		{
+			name:      "should return error when hardware requirements negative",
+			expectErr: true,
+			c:         invalidHardwareRequirements,
+		},
+		{
+			name:      "should succeed when hardware requirements correct",
+			expectErr: false,
+			c:         validHardwareRequirements,
+		},
+		{
+			name:      "should return error when host namespace invalid",
+			expectErr: true,
+			c:         invalidHostNamespaces,
+		},
+		{
+			name:      "should succeed when host namespaces correct",
+			expectErr: false,
+			c:         validHostNamespaces,
+		},
+		{
+			name:      "should return error when bootstrap secret key invalid",
+			expectErr: true,
+			c:         invalidBootstrapSecretKey,
+		},
+		{
+			name:      "should succeed when bootstrap secret key correct",
+			expectErr: false,
+			c:         validBootstrapSecretKey,
+		},
+		{
+			name:      "should return error when extra user data key missing",
+			expectErr: true,
+			c:         invalidExtraUserData,
+		},
+		{
+			name:      "should succeed when extra user data correct",
+			expectErr: false,
+			c:         validExtraUserData,
+		},
+		{
+			name:      "should return error when drain timeout negative",
+			expectErr: true,
+			c:         invalidNodeDrain,
+		},
+		{
+			name:      "should succeed when node drain correct",
+			expectErr: false,
+			c:         validNodeDrain,
+		},
+		{
+			name:      "should return error when host ref without name",
+			expectErr: true,
+			c:         invalidHostRef,
+		},
+		{
+			name:      "should succeed when host ref correct",
+			expectErr: false,
+			c:         validHostRef,
+		},
+		{
+			name:      "should return error when url template invalid",
+			expectErr: true,
+			c:         invalidURLTemplate,
+		},
+		{
+			name:      "should return error when checksum template refers to unknown field",
+			expectErr: true,
+			c:         invalidChecksumTemplate,
+		},
+		{
+			name:      "should succeed when templates correct",
+			expectErr: false,
+			c:         validTemplates,
+		},
 	}
 
 	for _, tt := range tests {
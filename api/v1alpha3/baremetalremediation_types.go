@@ -0,0 +1,161 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RemediationFinalizer allows BareMetalRemediationReconciler to power the
+	// BareMetalHost on again before removing the BareMetalRemediation from
+	// the apiserver.
+	RemediationFinalizer = "baremetalremediation.infrastructure.cluster.x-k8s.io"
+)
+
+// RemediationType is the type of remediation applied to an unhealthy
+// Machine.
+// +kubebuilder:validation:Enum=Reboot
+type RemediationType string
+
+const (
+	// RebootRemediationStrategy power cycles the BareMetalHost of the
+	// Machine. This is the default.
+	RebootRemediationStrategy RemediationType = "Reboot"
+)
+
+// RemediationPhase is the phase of a BareMetalRemediation.
+type RemediationPhase string
+
+const (
+	// PhaseRunning means the BareMetalHost is being power cycled.
+	PhaseRunning RemediationPhase = "Running"
+	// PhaseWaiting means the BareMetalHost was power cycled and the Node of
+	// the Machine is not ready yet.
+	PhaseWaiting RemediationPhase = "Waiting"
+	// PhaseSucceeded means the Node of the Machine is ready again after a
+	// power cycle.
+	PhaseSucceeded RemediationPhase = "Succeeded"
+	// PhaseFailed means all the power cycles failed and the Machine was
+	// deleted.
+	PhaseFailed RemediationPhase = "Failed"
+)
+
+// RemediationStrategy is how an unhealthy Machine is remediated before
+// falling back to its deletion.
+type RemediationStrategy struct {
+	// Type is the type of remediation. Defaults to Reboot.
+	// +optional
+	Type RemediationType `json:"type,omitempty"`
+
+	// RetryLimit is the number of power cycles attempted before the Machine
+	// is deleted. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RetryLimit int `json:"retryLimit,omitempty"`
+
+	// Timeout is the time given to each power cycle for the Node of the
+	// Machine to be ready again. Defaults to 10 minutes.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// BareMetalRemediationSpec defines the desired state of BareMetalRemediation
+type BareMetalRemediationSpec struct {
+	// Strategy is the remediation applied to the Machine.
+	// +optional
+	Strategy *RemediationStrategy `json:"strategy,omitempty"`
+}
+
+// RemediationAttempt records the timings of a power cycle of the
+// BareMetalHost.
+type RemediationAttempt struct {
+	// StartedAt is the time the power cycle started.
+	StartedAt metav1.Time `json:"startedAt"`
+
+	// PoweredOffAt is the time the BareMetalHost was found powered off.
+	// +optional
+	PoweredOffAt *metav1.Time `json:"poweredOffAt,omitempty"`
+
+	// PoweredOnAt is the time the BareMetalHost was found powered on again.
+	// +optional
+	PoweredOnAt *metav1.Time `json:"poweredOnAt,omitempty"`
+
+	// CompletedAt is the time the Node was found ready, or the time the
+	// attempt timed out.
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// TimedOut is true if the Node was not ready before the timeout.
+	// +optional
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
+// BareMetalRemediationStatus defines the observed state of BareMetalRemediation
+type BareMetalRemediationStatus struct {
+	// Phase is the phase of the remediation.
+	// +optional
+	Phase RemediationPhase `json:"phase,omitempty"`
+
+	// RetryCount is the number of power cycles attempted.
+	// +optional
+	RetryCount int `json:"retryCount,omitempty"`
+
+	// LastRemediated is the time the remediation completed, either with
+	// the Node ready or with the deletion of the Machine.
+	// +optional
+	LastRemediated *metav1.Time `json:"lastRemediated,omitempty"`
+
+	// Attempts records the power cycles of the BareMetalHost.
+	// +optional
+	Attempts []RemediationAttempt `json:"attempts,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:path=baremetalremediations,scope=Namespaced,categories=cluster-api,shortName=bmr;bmremediation
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="BareMetalRemediation current phase"
+// +kubebuilder:printcolumn:name="Retries",type="integer",JSONPath=".status.retryCount",description="Number of power cycles attempted"
+// +kubebuilder:printcolumn:name="Last Remediated",type="date",JSONPath=".status.lastRemediated",description="Time the remediation completed"
+
+// BareMetalRemediation is the Schema for the baremetalremediations API. It
+// remediates the unhealthy Machine of the same name, in the same namespace,
+// by power cycling its BareMetalHost, and deletes the Machine if the Node is
+// still not ready after the power cycles. It is created by hand: the Cluster
+// API version in use has no external remediation to create it.
+type BareMetalRemediation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BareMetalRemediationSpec   `json:"spec,omitempty"`
+	Status BareMetalRemediationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BareMetalRemediationList contains a list of BareMetalRemediation
+type BareMetalRemediationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BareMetalRemediation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BareMetalRemediation{}, &BareMetalRemediationList{})
+}
@@ -40,6 +40,35 @@ type HostSelector struct {
 	MatchExpressions []HostSelectorRequirement `json:"matchExpressions,omitempty"`
 }
 
+// HardwareRequirements specifies the minimal hardware a BareMetalHost must
+// have to be considered for claiming for a Machine. They are checked against
+// the hardware details found during the inspection of the BareMetalHost.
+type HardwareRequirements struct {
+	// MinCPUCount is the minimal number of CPUs.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinCPUCount int `json:"minCPUCount,omitempty"`
+
+	// MinRAMMebibytes is the minimal amount of RAM in MiB.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinRAMMebibytes int `json:"minRAMMebibytes,omitempty"`
+
+	// MinDiskSizeGibibytes is the minimal size in GiB of the biggest disk.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinDiskSizeGibibytes int `json:"minDiskSizeGibibytes,omitempty"`
+
+	// MinNICCount is the minimal number of network interfaces.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinNICCount int `json:"minNICCount,omitempty"`
+
+	// CPUArchitecture is the required CPU architecture, e.g. x86_64.
+	// +optional
+	CPUArchitecture string `json:"cpuArchitecture,omitempty"`
+}
+
 type HostSelectorRequirement struct {
 	Key      string             `json:"key"`
 	Operator selection.Operator `json:"operator"`
@@ -48,9 +77,12 @@ type HostSelectorRequirement struct {
 
 // Image holds the details of an image to use during provisioning.
 type Image struct {
-	// URL is a location of an image to deploy.
+	// URL is a location of an image to deploy. It can be a Go template,
+	// rendered for the chosen BareMetalHost with its .Name, .Namespace,
+	// .Labels and CPU .Arch.
 	URL string `json:"url"`
 
-	// Checksum is a md5sum value or a URL to retrieve one.
+	// Checksum is a md5sum value or a URL to retrieve one. It can be a Go
+	// template, like the URL.
 	Checksum string `json:"checksum"`
 }
@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// ImageTemplateData is the data the Go templates of the Image URL and
+// Checksum are rendered with, describing the chosen BareMetalHost.
+// +kubebuilder:object:generate=false
+type ImageTemplateData struct {
+	// Name is the name of the BareMetalHost.
+	Name string
+	// Namespace is the namespace of the BareMetalHost.
+	Namespace string
+	// Labels are the labels of the BareMetalHost.
+	Labels map[string]string
+	// Arch is the CPU architecture of the BareMetalHost found during the
+	// inspection, e.g. x86_64 or aarch64.
+	Arch string
+}
+
+// RenderImageTemplate renders a template of the Image URL or Checksum with
+// the data of the BareMetalHost. Referring to a label the host does not have,
+// as in .Labels.os, is an error, while index .Labels "os" renders an empty
+// string for it. Text without template actions is returned unchanged.
+func RenderImageTemplate(text string, data ImageTemplateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("image").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// validateImageTemplate checks that the text is a valid template that only
+// refers to the fields of ImageTemplateData. The template is not executed,
+// since its result depends on the data of the host.
+func validateImageTemplate(text string) error {
+	if !strings.Contains(text, "{{") {
+		return nil
+	}
+	tmpl, err := template.New("image").Parse(text)
+	if err != nil {
+		return err
+	}
+	if tmpl.Tree == nil {
+		return nil
+	}
+	return checkTemplateNode(tmpl.Tree.Root, true)
+}
+
+// checkTemplateField returns an error if name is not a field of
+// ImageTemplateData.
+func checkTemplateField(name string) error {
+	if _, ok := reflect.TypeOf(ImageTemplateData{}).FieldByName(name); !ok {
+		return fmt.Errorf("unknown field %s in template", name)
+	}
+	return nil
+}
+
+// checkTemplateNode checks the fields referred to by the node and its
+// children. atRoot tells whether the dot is the ImageTemplateData, which is
+// not the case within range and with.
+func checkTemplateNode(node parse.Node, atRoot bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkTemplateNode(child, atRoot); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkTemplateNode(n.Pipe, atRoot)
+	case *parse.IfNode:
+		return checkTemplateBranch(&n.BranchNode, atRoot, atRoot)
+	case *parse.RangeNode:
+		return checkTemplateBranch(&n.BranchNode, atRoot, false)
+	case *parse.WithNode:
+		return checkTemplateBranch(&n.BranchNode, atRoot, false)
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			return checkTemplateNode(n.Pipe, atRoot)
+		}
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				if err := checkTemplateNode(arg, atRoot); err != nil {
+					return err
+				}
+			}
+		}
+	case *parse.FieldNode:
+		if atRoot {
+			return checkTemplateField(n.Ident[0])
+		}
+	case *parse.VariableNode:
+		// $ is always the ImageTemplateData
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			return checkTemplateField(n.Ident[1])
+		}
+	case *parse.ChainNode:
+		if _, ok := n.Node.(*parse.DotNode); ok && atRoot {
+			return checkTemplateField(n.Field[0])
+		}
+		return checkTemplateNode(n.Node, atRoot)
+	}
+	return nil
+}
+
+// checkTemplateBranch checks the pipeline and the lists of an if, range or
+// with node. inList tells whether the dot is the ImageTemplateData within
+// the list, the else list keeping the dot of the pipeline.
+func checkTemplateBranch(n *parse.BranchNode, atRoot bool, inList bool) error {
+	if err := checkTemplateNode(n.Pipe, atRoot); err != nil {
+		return err
+	}
+	if err := checkTemplateNode(n.List, inList); err != nil {
+		return err
+	}
+	return checkTemplateNode(n.ElseList, atRoot)
+}
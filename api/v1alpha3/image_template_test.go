@@ -0,0 +1,142 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRenderImageTemplate(t *testing.T) {
+	data := ImageTemplateData{
+		Name:      "host-0",
+		Namespace: "inventory",
+		Labels:    map[string]string{"os": "rhcos"},
+		Arch:      "aarch64",
+	}
+
+	tests := []struct {
+		name      string
+		text      string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "should return text without template unchanged",
+			text:     "http://172.22.0.1/images/image.qcow2",
+			expected: "http://172.22.0.1/images/image.qcow2",
+		},
+		{
+			name:     "should render architecture and label",
+			text:     "http://172.22.0.1/images/{{ .Labels.os }}-{{ .Arch }}.qcow2",
+			expected: "http://172.22.0.1/images/rhcos-aarch64.qcow2",
+		},
+		{
+			name:     "should render host name and namespace",
+			text:     "http://172.22.0.1/{{ .Namespace }}/{{ .Name }}.qcow2",
+			expected: "http://172.22.0.1/inventory/host-0.qcow2",
+		},
+		{
+			name:      "should return error when label missing",
+			text:      "http://172.22.0.1/images/{{ .Labels.rack }}.qcow2",
+			expectErr: true,
+		},
+		{
+			name:     "should render missing label with index as empty string",
+			text:     "http://172.22.0.1/images/{{ index .Labels \"rack\" }}.qcow2",
+			expected: "http://172.22.0.1/images/.qcow2",
+		},
+		{
+			name:      "should return error when template invalid",
+			text:      "http://172.22.0.1/images/{{ .Arch.qcow2",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			rendered, err := RenderImageTemplate(tt.text, data)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(rendered).To(Equal(tt.expected))
+			}
+		})
+	}
+}
+
+func TestValidateImageTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		expectErr bool
+	}{
+		{
+			name: "should accept text without template",
+			text: "http://172.22.0.1/images/image.qcow2",
+		},
+		{
+			name: "should accept fields of the host",
+			text: "http://172.22.0.1/{{ .Namespace }}/{{ .Name }}-{{ .Labels.os }}-{{ $.Arch }}.qcow2",
+		},
+		{
+			name: "should accept functions failing on empty data",
+			text: "http://172.22.0.1/images/{{ slice .Name 0 3 }}.qcow2",
+		},
+		{
+			name: "should accept fields of the dot within with",
+			text: "http://172.22.0.1/images/{{ with .Labels }}{{ .os }}{{ else }}{{ .Arch }}{{ end }}.qcow2",
+		},
+		{
+			name:      "should reject unknown field",
+			text:      "http://172.22.0.1/images/{{ .Architecture }}.qcow2",
+			expectErr: true,
+		},
+		{
+			name:      "should reject unknown field in function argument",
+			text:      "http://172.22.0.1/images/{{ printf \"%s\" (.Labels.os | printf \"%s-%s\" .Version) }}.qcow2",
+			expectErr: true,
+		},
+		{
+			name:      "should reject unknown field of the root within with",
+			text:      "http://172.22.0.1/images/{{ with .Labels }}{{ $.Architecture }}{{ end }}.qcow2",
+			expectErr: true,
+		},
+		{
+			name:      "should reject invalid template",
+			text:      "http://172.22.0.1/images/{{ .Arch.qcow2",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := validateImageTemplate(tt.text)
+			if tt.expectErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ package v1alpha3
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	apiv1alpha3 "sigs.k8s.io/cluster-api/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/errors"
@@ -134,6 +135,13 @@ func (in *BareMetalClusterStatus) DeepCopyInto(out *BareMetalClusterStatus) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.FailureDomains != nil {
+		in, out := &in.FailureDomains, &out.FailureDomains
+		*out = make(apiv1alpha3.FailureDomains, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalClusterStatus.
@@ -219,7 +227,34 @@ func (in *BareMetalMachineSpec) DeepCopyInto(out *BareMetalMachineSpec) {
 		*out = new(v1.SecretReference)
 		**out = **in
 	}
+	if in.ExtraUserData != nil {
+		in, out := &in.ExtraUserData, &out.ExtraUserData
+		*out = make([]v1.SecretKeySelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.HostSelector.DeepCopyInto(&out.HostSelector)
+	if in.HardwareRequirements != nil {
+		in, out := &in.HardwareRequirements, &out.HardwareRequirements
+		*out = new(HardwareRequirements)
+		**out = **in
+	}
+	if in.HostNamespaces != nil {
+		in, out := &in.HostNamespaces, &out.HostNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HostRef != nil {
+		in, out := &in.HostRef, &out.HostRef
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+	if in.NodeDrain != nil {
+		in, out := &in.NodeDrain, &out.NodeDrain
+		*out = new(NodeDrainPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalMachineSpec.
@@ -254,6 +289,31 @@ func (in *BareMetalMachineStatus) DeepCopyInto(out *BareMetalMachineStatus) {
 		*out = make(apiv1alpha3.MachineAddresses, len(*in))
 		copy(*out, *in)
 	}
+	if in.HostSelection != nil {
+		in, out := &in.HostSelection, &out.HostSelection
+		*out = new(HostSelectionStatus)
+		**out = **in
+	}
+	if in.ImageUpgrade != nil {
+		in, out := &in.ImageUpgrade, &out.ImageUpgrade
+		*out = new(ImageUpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeDrain != nil {
+		in, out := &in.NodeDrain, &out.NodeDrain
+		*out = new(NodeDrainStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deprovisioning != nil {
+		in, out := &in.Deprovisioning, &out.Deprovisioning
+		*out = new(DeprovisioningStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PowerAction != nil {
+		in, out := &in.PowerAction, &out.PowerAction
+		*out = new(PowerActionStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalMachineStatus.
@@ -356,6 +416,157 @@ func (in *BareMetalMachineTemplateSpec) DeepCopy() *BareMetalMachineTemplateSpec
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalRemediation) DeepCopyInto(out *BareMetalRemediation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalRemediation.
+func (in *BareMetalRemediation) DeepCopy() *BareMetalRemediation {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalRemediation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BareMetalRemediation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalRemediationList) DeepCopyInto(out *BareMetalRemediationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BareMetalRemediation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalRemediationList.
+func (in *BareMetalRemediationList) DeepCopy() *BareMetalRemediationList {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalRemediationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BareMetalRemediationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalRemediationSpec) DeepCopyInto(out *BareMetalRemediationSpec) {
+	*out = *in
+	if in.Strategy != nil {
+		in, out := &in.Strategy, &out.Strategy
+		*out = new(RemediationStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalRemediationSpec.
+func (in *BareMetalRemediationSpec) DeepCopy() *BareMetalRemediationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalRemediationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalRemediationStatus) DeepCopyInto(out *BareMetalRemediationStatus) {
+	*out = *in
+	if in.LastRemediated != nil {
+		in, out := &in.LastRemediated, &out.LastRemediated
+		*out = (*in).DeepCopy()
+	}
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]RemediationAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalRemediationStatus.
+func (in *BareMetalRemediationStatus) DeepCopy() *BareMetalRemediationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalRemediationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeprovisioningStatus) DeepCopyInto(out *DeprovisioningStatus) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeprovisioningStatus.
+func (in *DeprovisioningStatus) DeepCopy() *DeprovisioningStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeprovisioningStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HardwareRequirements) DeepCopyInto(out *HardwareRequirements) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareRequirements.
+func (in *HardwareRequirements) DeepCopy() *HardwareRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(HardwareRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostSelectionStatus) DeepCopyInto(out *HostSelectionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostSelectionStatus.
+func (in *HostSelectionStatus) DeepCopy() *HostSelectionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HostSelectionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HostSelector) DeepCopyInto(out *HostSelector) {
 	*out = *in
@@ -419,3 +630,138 @@ func (in *Image) DeepCopy() *Image {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageUpgradeStatus) DeepCopyInto(out *ImageUpgradeStatus) {
+	*out = *in
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageUpgradeStatus.
+func (in *ImageUpgradeStatus) DeepCopy() *ImageUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDrainPolicy) DeepCopyInto(out *NodeDrainPolicy) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDrainPolicy.
+func (in *NodeDrainPolicy) DeepCopy() *NodeDrainPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDrainPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDrainStatus) DeepCopyInto(out *NodeDrainStatus) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDrainStatus.
+func (in *NodeDrainStatus) DeepCopy() *NodeDrainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDrainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerActionStatus) DeepCopyInto(out *PowerActionStatus) {
+	*out = *in
+	in.RequestedAt.DeepCopyInto(&out.RequestedAt)
+	if in.PoweredOffAt != nil {
+		in, out := &in.PoweredOffAt, &out.PoweredOffAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerActionStatus.
+func (in *PowerActionStatus) DeepCopy() *PowerActionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerActionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationAttempt) DeepCopyInto(out *RemediationAttempt) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.PoweredOffAt != nil {
+		in, out := &in.PoweredOffAt, &out.PoweredOffAt
+		*out = (*in).DeepCopy()
+	}
+	if in.PoweredOnAt != nil {
+		in, out := &in.PoweredOnAt, &out.PoweredOnAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationAttempt.
+func (in *RemediationAttempt) DeepCopy() *RemediationAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationStrategy) DeepCopyInto(out *RemediationStrategy) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationStrategy.
+func (in *RemediationStrategy) DeepCopy() *RemediationStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -23,6 +23,8 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+
 	// TODO Why blank import ?
 	_ "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
@@ -39,6 +41,7 @@ type ClusterManagerInterface interface {
 	Create(context.Context) error
 	Delete() error
 	UpdateClusterStatus() error
+	UpdateFailureDomains(context.Context) error
 	SetFinalizer()
 	UnsetFinalizer()
 	CountDescendants(context.Context) (int, error)
@@ -51,6 +54,9 @@ type ClusterManager struct {
 	Cluster          *capi.Cluster
 	BareMetalCluster *capm3.BareMetalCluster
 	Log              logr.Logger
+	// AllowedHostNamespaces is the list of namespaces, other than the one
+	// of the cluster, in which the BareMetalMachines may claim hosts
+	AllowedHostNamespaces []string
 	// name string
 }
 
@@ -152,6 +158,86 @@ func (s *ClusterManager) UpdateClusterStatus() error {
 	return nil
 }
 
+// UpdateFailureDomains sets the failure domains in the BareMetalCluster
+// status from the values of the FailureDomainLabel found on the
+// BareMetalHosts the machines of the cluster may claim.
+func (s *ClusterManager) UpdateFailureDomains(ctx context.Context) error {
+	failureDomainLabel := s.BareMetalCluster.Spec.FailureDomainLabel
+	if failureDomainLabel == "" {
+		s.BareMetalCluster.Status.FailureDomains = nil
+		return nil
+	}
+
+	hostNamespaces, err := s.hostNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	failureDomains := capi.FailureDomains{}
+	for _, namespace := range hostNamespaces {
+		hosts := bmh.BareMetalHostList{}
+		err := s.client.List(ctx, &hosts, client.InNamespace(namespace))
+		if err != nil {
+			return errors.Wrap(err, "failed to list BareMetalHosts")
+		}
+		for _, host := range hosts.Items {
+			failureDomain := host.Labels[failureDomainLabel]
+			if failureDomain == "" {
+				continue
+			}
+			failureDomains[failureDomain] = capi.FailureDomainSpec{
+				ControlPlane: true,
+			}
+		}
+	}
+	if len(failureDomains) == 0 {
+		failureDomains = nil
+	}
+	s.BareMetalCluster.Status.FailureDomains = failureDomains
+	return nil
+}
+
+// hostNamespaces returns the namespace of the cluster and the allowed
+// namespaces listed in the hostNamespaces of the BareMetalMachines of the
+// cluster or of the BareMetalMachineTemplates of its namespace.
+func (s *ClusterManager) hostNamespaces(ctx context.Context) ([]string, error) {
+	namespace := s.BareMetalCluster.Namespace
+	namespaces := []string{namespace}
+	addNamespaces := func(hostNamespaces []string) {
+		for _, hostNamespace := range hostNamespaces {
+			if util.Contains(namespaces, hostNamespace) ||
+				!util.Contains(s.AllowedHostNamespaces, hostNamespace) {
+				continue
+			}
+			namespaces = append(namespaces, hostNamespace)
+		}
+	}
+	if len(s.AllowedHostNamespaces) == 0 {
+		return namespaces, nil
+	}
+
+	bmMachines := capm3.BareMetalMachineList{}
+	err := s.client.List(ctx, &bmMachines, client.InNamespace(namespace),
+		client.MatchingLabels{capi.ClusterLabelName: s.Cluster.Name},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list BareMetalMachines")
+	}
+	for _, bmMachine := range bmMachines.Items {
+		addNamespaces(bmMachine.Spec.HostNamespaces)
+	}
+
+	bmMachineTemplates := capm3.BareMetalMachineTemplateList{}
+	err = s.client.List(ctx, &bmMachineTemplates, client.InNamespace(namespace))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list BareMetalMachineTemplates")
+	}
+	for _, bmMachineTemplate := range bmMachineTemplates.Items {
+		addNamespaces(bmMachineTemplate.Spec.Template.Spec.HostNamespaces)
+	}
+	return namespaces, nil
+}
+
 // setError sets the FailureMessage and FailureReason fields on the machine and logs
 // the message. It assumes the reason is invalid configuration, since that is
 // currently the only relevant MachineStatusError choice.
@@ -24,6 +24,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	_ "github.com/go-logr/logr"
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
 	infrav1 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	_ "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -360,6 +361,125 @@ var _ = Describe("BareMetalCluster manager", func() {
 		},
 		descendantsTestCases...,
 	)
+
+	type testCaseFailureDomains struct {
+		FailureDomainLabel     string
+		AllowedHostNamespaces  []string
+		Hosts                  []runtime.Object
+		ExpectedFailureDomains clusterv1.FailureDomains
+	}
+
+	failureDomainHost := func(name string, labels map[string]string) *bmh.BareMetalHost {
+		return &bmh.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespaceName,
+				Labels:    labels,
+			},
+		}
+	}
+
+	sharedHost := func(name string, labels map[string]string) *bmh.BareMetalHost {
+		host := failureDomainHost(name, labels)
+		host.Namespace = "shared"
+		return host
+	}
+
+	sharedBMMachine := &infrav1.BareMetalMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bmmachine",
+			Namespace: namespaceName,
+			Labels:    map[string]string{clusterv1.ClusterLabelName: clusterName},
+		},
+		Spec: infrav1.BareMetalMachineSpec{
+			HostNamespaces: []string{namespaceName, "shared"},
+		},
+	}
+
+	DescribeTable("Test UpdateFailureDomains",
+		func(tc testCaseFailureDomains) {
+			bmCluster := newBareMetalCluster(baremetalClusterName, bmcOwnerRef,
+				&infrav1.BareMetalClusterSpec{
+					FailureDomainLabel: tc.FailureDomainLabel,
+				}, nil,
+			)
+			c := fakeclient.NewFakeClientWithScheme(setupScheme(), tc.Hosts...)
+			clusterMgr := &ClusterManager{
+				client:                c,
+				BareMetalCluster:      bmCluster,
+				Cluster:               newCluster(clusterName),
+				Log:                   klogr.New(),
+				AllowedHostNamespaces: tc.AllowedHostNamespaces,
+			}
+
+			err := clusterMgr.UpdateFailureDomains(context.TODO())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bmCluster.Status.FailureDomains).To(
+				Equal(tc.ExpectedFailureDomains),
+			)
+		},
+		Entry("No failure domain label", testCaseFailureDomains{
+			Hosts: []runtime.Object{
+				failureDomainHost("host1", map[string]string{"rack": "rack1"}),
+			},
+			ExpectedFailureDomains: nil,
+		}),
+		Entry("No labelled host", testCaseFailureDomains{
+			FailureDomainLabel: "rack",
+			Hosts: []runtime.Object{
+				failureDomainHost("host1", nil),
+			},
+			ExpectedFailureDomains: nil,
+		}),
+		Entry("Labelled hosts", testCaseFailureDomains{
+			FailureDomainLabel: "rack",
+			Hosts: []runtime.Object{
+				failureDomainHost("host1", map[string]string{"rack": "rack1"}),
+				failureDomainHost("host2", map[string]string{"rack": "rack2"}),
+				failureDomainHost("host3", map[string]string{"rack": "rack1"}),
+				failureDomainHost("host4", nil),
+			},
+			ExpectedFailureDomains: clusterv1.FailureDomains{
+				"rack1": clusterv1.FailureDomainSpec{ControlPlane: true},
+				"rack2": clusterv1.FailureDomainSpec{ControlPlane: true},
+			},
+		}),
+		Entry("Hosts of an allowed namespace", testCaseFailureDomains{
+			FailureDomainLabel:    "rack",
+			AllowedHostNamespaces: []string{"shared"},
+			Hosts: []runtime.Object{
+				failureDomainHost("host1", map[string]string{"rack": "rack1"}),
+				sharedHost("host2", map[string]string{"rack": "rack2"}),
+				sharedBMMachine,
+			},
+			ExpectedFailureDomains: clusterv1.FailureDomains{
+				"rack1": clusterv1.FailureDomainSpec{ControlPlane: true},
+				"rack2": clusterv1.FailureDomainSpec{ControlPlane: true},
+			},
+		}),
+		Entry("Hosts of a namespace not used by the cluster", testCaseFailureDomains{
+			FailureDomainLabel:    "rack",
+			AllowedHostNamespaces: []string{"shared"},
+			Hosts: []runtime.Object{
+				failureDomainHost("host1", map[string]string{"rack": "rack1"}),
+				sharedHost("host2", map[string]string{"rack": "rack2"}),
+			},
+			ExpectedFailureDomains: clusterv1.FailureDomains{
+				"rack1": clusterv1.FailureDomainSpec{ControlPlane: true},
+			},
+		}),
+		Entry("Hosts of a namespace that is not allowed", testCaseFailureDomains{
+			FailureDomainLabel: "rack",
+			Hosts: []runtime.Object{
+				failureDomainHost("host1", map[string]string{"rack": "rack1"}),
+				sharedHost("host2", map[string]string{"rack": "rack2"}),
+				sharedBMMachine,
+			},
+			ExpectedFailureDomains: clusterv1.FailureDomains{
+				"rack1": clusterv1.FailureDomainSpec{ControlPlane: true},
+			},
+		}),
+	)
 })
 
 func newBMClusterSetup(tc testCaseBMClusterManager) (*ClusterManager, error) {
@@ -20,7 +20,6 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
-	"math/rand"
 	"strings"
 	"time"
 
@@ -82,6 +81,33 @@ type MachineManager struct {
 	Machine          *capi.Machine
 	BareMetalMachine *capm3.BareMetalMachine
 	Log              logr.Logger
+
+	MachineManagerConfig
+}
+
+// MachineManagerConfig is the configuration of the MachineManagers, common
+// to all the BareMetalMachines.
+type MachineManagerConfig struct {
+	// AllowedHostNamespaces is the list of namespaces, other than the one
+	// of the Machine, in which BareMetalHosts can be claimed.
+	AllowedHostNamespaces []string
+
+	// QuarantineThreshold is the number of provisioning failures after
+	// which a BareMetalHost is quarantined. 0 disables the quarantine.
+	QuarantineThreshold int
+
+	// UserDataSizeLimit is the maximal size in bytes of the user data
+	// written for a BareMetalHost, after compression. 0 disables the limit.
+	UserDataSizeLimit int
+
+	// RemoteClientGetter returns the client of the workload cluster, used to
+	// drain the Nodes. Defaults to remote.NewClusterClient.
+	RemoteClientGetter ClientGetter
+
+	// DeprovisionTimeout is the time after which a BareMetalHost that is
+	// still not deprovisioned sets the BareMetalMachine being deleted in
+	// error. 0 disables the timeout.
+	DeprovisionTimeout time.Duration
 }
 
 // NewMachineManager returns a new helper for managing a machine
@@ -165,6 +191,30 @@ func (m *MachineManager) GetBaremetalHostID(ctx context.Context) (*string, error
 	if host.Status.Provisioning.State == bmh.StateProvisioned {
 		return pointer.StringPtr(string(host.ObjectMeta.UID)), nil
 	}
+
+	// Count the provisioning failure of the host, if any, while waiting
+	err = m.recordHostFailure(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh the user data if the bootstrap data changed before the host
+	// started provisioning
+	err = m.syncUserData(ctx, host)
+	if isInvalidUserDataError(err) {
+		// Do not requeue, the user data or the configuration must be changed
+		m.setError(err.Error(), capierrors.InvalidConfigurationMachineError)
+		return nil, nil
+	} else if err != nil {
+		m.setError("Failed to refresh the UserData of the BareMetalMachine",
+			capierrors.CreateMachineError,
+		)
+		return nil, err
+	}
+
+	// Power actions are only applied to a provisioned host
+	m.recordPendingPowerAction(host)
+
 	m.Log.Info("Provisioning BaremetalHost, requeuing")
 	return nil, &RequeueAfterError{RequeueAfter: requeueAfter}
 }
@@ -187,6 +237,13 @@ func (m *MachineManager) Associate(ctx context.Context) error {
 		return nil
 	}
 
+	hostNamespaces, err := m.hostNamespaces()
+	if err != nil {
+		// Do not requeue, the configuration must be fixed
+		m.setError(err.Error(), capierrors.InvalidConfigurationMachineError)
+		return nil
+	}
+
 	// clear an error if one was previously set
 	m.clearError()
 
@@ -199,9 +256,26 @@ func (m *MachineManager) Associate(ctx context.Context) error {
 		return err
 	}
 
-	// no BMH found, trying to choose from available ones
-	if host == nil {
-		host, err = m.chooseHost(ctx)
+	if host == nil && m.BareMetalMachine.Spec.HostRef != nil {
+		// no BMH associated, claiming the pinned one. If it can not be
+		// claimed, the error is already set.
+		host, err = m.claimPinnedHost(ctx)
+		if err != nil || host == nil {
+			return err
+		}
+		m.Log.Info("Associating machine with pinned host", "host", host.Name)
+	} else if host == nil {
+		// A quarantined host can be chosen once an operator released it
+		err = m.releaseQuarantinedHosts(ctx, hostNamespaces)
+		if err != nil {
+			m.setError("Failed to release the quarantined BaremetalHosts",
+				capierrors.CreateMachineError,
+			)
+			return err
+		}
+
+		// no BMH found, trying to choose from available ones
+		host, err = m.chooseHost(ctx, hostNamespaces)
 		if err != nil {
 			m.setError("Failed to pick a BaremetalHost for the BareMetalMachine",
 				capierrors.CreateMachineError,
@@ -220,7 +294,11 @@ func (m *MachineManager) Associate(ctx context.Context) error {
 	// A machine bootstrap not ready case is caught in the controller
 	// ReconcileNormal function
 	err = m.GetUserData(ctx, host)
-	if err != nil {
+	if isInvalidUserDataError(err) {
+		// Do not requeue, the user data or the configuration must be changed
+		m.setError(err.Error(), capierrors.InvalidConfigurationMachineError)
+		return nil
+	} else if err != nil {
 		m.setError("Failed to set the UserData for the BareMetalMachine",
 			capierrors.CreateMachineError,
 		)
@@ -261,26 +339,34 @@ func (m *MachineManager) Associate(ctx context.Context) error {
 }
 
 // GetUserData gets the UserData from the machine and exposes it as a secret
-// for the BareMetalHost. The UserData might already be in a secret with
-// CABPK v0.3.0+, but if it is in a different namespace than the BareMetalHost,
-// then we need to create the secret. Same as if the UserData is in the data
-// field.
+// for the BareMetalHost. The UserData might be in a secret with CABPK
+// v0.3.0+, or in the data field. It is merged with the extra user data of the
+// BareMetalMachine, if any, and always written to a secret created in the
+// namespace of the BareMetalHost. The format of the UserData is recorded in a
+// label of the created secret.
 func (m *MachineManager) GetUserData(ctx context.Context, host *bmh.BareMetalHost) error {
-	var err error
-	var decodedUserDataBytes []byte
-	// if datasecretname is set and BaremetalHost and Machine are in the same
-	// namespace, just pass the reference
-	if m.Machine.Spec.Bootstrap.DataSecretName != nil &&
-		host.Namespace == m.Machine.Namespace {
-		m.BareMetalMachine.Spec.UserData = &corev1.SecretReference{
-			Name:      *m.Machine.Spec.Bootstrap.DataSecretName,
-			Namespace: m.Machine.Namespace,
-		}
+	// If we do not have DataSecretName or Data then exit
+	if m.Machine.Spec.Bootstrap.DataSecretName == nil &&
+		m.Machine.Spec.Bootstrap.Data == nil {
 		return nil
+	}
+
+	userData, format, err := m.userData(ctx)
+	if err != nil {
+		return err
+	}
+	return m.writeUserData(ctx, host, userData, format)
+}
 
-	} else if m.Machine.Spec.Bootstrap.DataSecretName != nil &&
-		host.Namespace != m.Machine.Namespace {
-		// If they are in different namespaces, create a new secret in BMH namespace
+// userData returns the UserData written for the BareMetalHost, the bootstrap
+// data of the Machine merged with the extra user data, and the format of the
+// bootstrap data.
+func (m *MachineManager) userData(ctx context.Context) ([]byte, BootstrapFormat, error) {
+	var err error
+	var decodedUserDataBytes []byte
+	var format BootstrapFormat
+	if m.Machine.Spec.Bootstrap.DataSecretName != nil {
+		secretKey := m.bootstrapSecretKey()
 		capiBootstrapSecret := corev1.Secret{}
 		capikey := client.ObjectKey{
 			Name:      *m.Machine.Spec.Bootstrap.DataSecretName,
@@ -288,13 +374,23 @@ func (m *MachineManager) GetUserData(ctx context.Context, host *bmh.BareMetalHos
 		}
 		err := m.client.Get(ctx, capikey, &capiBootstrapSecret)
 		if err != nil {
-			return err
+			return nil, "", err
+		}
+		var ok bool
+		decodedUserDataBytes, ok = capiBootstrapSecret.Data[secretKey]
+		if !ok {
+			return nil, "", errors.Errorf("key %s not found in the bootstrap data secret %s",
+				secretKey, capiBootstrapSecret.Name,
+			)
+		}
+		// The bootstrap provider may give the format, used as a label value
+		format = BootstrapFormat(capiBootstrapSecret.Data["format"])
+		if format != "" && !isKnownBootstrapFormat(format) {
+			m.Log.Info("Unknown format of the bootstrap data, detecting it",
+				"format", format,
+			)
+			format = ""
 		}
-		decodedUserDataBytes = capiBootstrapSecret.Data["value"]
-
-	} else if m.Machine.Spec.Bootstrap.Data == nil {
-		// If we do not have DataSecretName or Data then exit
-		return nil
 
 	} else if m.Machine.Spec.Bootstrap.Data != nil {
 		// If we have Data, use it
@@ -302,10 +398,38 @@ func (m *MachineManager) GetUserData(ctx context.Context, host *bmh.BareMetalHos
 		// decode the base64 cloud-config
 		decodedUserDataBytes, err = base64.StdEncoding.DecodeString(decodedUserData)
 		if err != nil {
-			return err
+			return nil, "", err
 		}
 	}
 
+	if format == "" {
+		format = detectBootstrapFormat(decodedUserDataBytes)
+	}
+	decodedUserDataBytes, err = m.mergeExtraUserData(ctx, decodedUserDataBytes,
+		format,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	decodedUserDataBytes, err = m.limitUserData(decodedUserDataBytes, format)
+	if err != nil {
+		return nil, "", err
+	}
+	return decodedUserDataBytes, format, nil
+}
+
+// writeUserData writes the UserData to the secret created for the
+// BareMetalHost, and references it in the BareMetalMachine.
+func (m *MachineManager) writeUserData(ctx context.Context,
+	host *bmh.BareMetalHost, decodedUserDataBytes []byte, format BootstrapFormat,
+) error {
+	secretLabels := map[string]string{
+		capi.ClusterLabelName: m.Machine.Spec.ClusterName,
+	}
+	if format != "" {
+		secretLabels[BootstrapFormatLabel] = string(format)
+	}
+
 	bootstrapSecret := &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Secret",
@@ -314,9 +438,7 @@ func (m *MachineManager) GetUserData(ctx context.Context, host *bmh.BareMetalHos
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      m.BareMetalMachine.Name + "-user-data",
 			Namespace: host.Namespace,
-			Labels: map[string]string{
-				capi.ClusterLabelName: m.Machine.Spec.ClusterName,
-			},
+			Labels:    secretLabels,
 			OwnerReferences: []metav1.OwnerReference{
 				metav1.OwnerReference{
 					Controller: pointer.BoolPtr(true),
@@ -339,7 +461,7 @@ func (m *MachineManager) GetUserData(ctx context.Context, host *bmh.BareMetalHos
 		Name:      m.BareMetalMachine.Name + "-user-data",
 		Namespace: host.Namespace,
 	}
-	err = m.client.Get(ctx, key, &tmpBootstrapSecret)
+	err := m.client.Get(ctx, key, &tmpBootstrapSecret)
 	if apierrors.IsNotFound(err) {
 		// Create the secret with user data
 		err = m.client.Create(ctx, bootstrapSecret)
@@ -374,6 +496,15 @@ func (m *MachineManager) Delete(ctx context.Context) error {
 		return err
 	}
 
+	// The host may have been claimed without the BareMetalMachine being
+	// annotated, if the association failed midway.
+	if host == nil {
+		host, err = m.getClaimedHost(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	if host != nil && host.Spec.ConsumerRef != nil {
 		host.OwnerReferences = m.DeleteOwnerRef(host.OwnerReferences)
 		// don't remove the ConsumerRef if it references some other bare metal machine
@@ -400,6 +531,13 @@ func (m *MachineManager) Delete(ctx context.Context) error {
 		}
 
 		if host.Spec.Image != nil || host.Spec.Online || host.Spec.UserData != nil {
+			// drain the Node first, if enabled
+			err = m.drainNode(ctx, host)
+			if err != nil {
+				return err
+			}
+
+			m.startDeprovisioning()
 			host.Spec.Image = nil
 			host.Spec.Online = false
 			host.Spec.UserData = nil
@@ -426,12 +564,33 @@ func (m *MachineManager) Delete(ctx context.Context) error {
 			// host is powered off
 			waiting = host.Status.PoweredOn
 		}
+		if waiting && m.forceReleaseRequested() {
+			err = m.createHostEvent(ctx, host, corev1.EventTypeWarning,
+				"ForceReleased", fmt.Sprintf(
+					"Host released by BareMetalMachine %s without waiting for the deprovisioning",
+					m.BareMetalMachine.Name,
+				),
+			)
+			if err != nil {
+				return err
+			}
+			m.Log.Info("Force releasing BaremetalHost", "host", host.Name)
+			waiting = false
+		}
 		if waiting {
+			err = m.checkDeprovisionTimeout(ctx, host)
+			if err != nil {
+				return err
+			}
 			m.Log.Info("Deprovisioning BaremetalHost, requeuing")
 			return &RequeueAfterError{RequeueAfter: requeueAfter}
 		}
 
 		host.Spec.ConsumerRef = nil
+		m.recordLastConsumer(host)
+		// the next consumer of the host must not find it kept powered off
+		setHostPowerOffAnnotation(host, false)
+		delete(host.Annotations, RemediationPowerOffAnnotation)
 		if host.Labels != nil && host.Labels[capi.ClusterLabelName] == m.Machine.Spec.ClusterName {
 			delete(host.Labels, capi.ClusterLabelName)
 		}
@@ -445,49 +604,48 @@ func (m *MachineManager) Delete(ctx context.Context) error {
 		}
 	}
 
-	// Delete created secret, if data was set without DataSecretName or if
-	// BareMetalHost and Machine are in different namespaces.
-	if (m.Machine.Spec.Bootstrap.DataSecretName == nil &&
-		m.Machine.Spec.Bootstrap.Data != nil) ||
-		(m.Machine.Spec.Bootstrap.DataSecretName != nil &&
-			m.Machine.Namespace != host.Namespace) {
+	// Delete the user data secret, always created by the machine manager
+	userData := m.BareMetalMachine.Spec.UserData
+	if (m.Machine.Spec.Bootstrap.DataSecretName != nil ||
+		m.Machine.Spec.Bootstrap.Data != nil) &&
+		userData != nil && userData.Name == m.BareMetalMachine.Name+"-user-data" {
 		m.Log.Info("Deleting User data secret for machine")
-		tmpBootstrapSecret := corev1.Secret{}
-		key := client.ObjectKey{
-			Name:      m.BareMetalMachine.Name + "-user-data",
-			Namespace: host.Namespace,
-		}
-		err = m.client.Get(ctx, key, &tmpBootstrapSecret)
-		if err != nil && !apierrors.IsNotFound(err) {
+		err = m.deleteSecret(ctx, client.ObjectKey{
+			Name:      userData.Name,
+			Namespace: userData.Namespace,
+		})
+		if err != nil {
 			m.setError("Failed to delete userdata secret",
 				capierrors.DeleteMachineError,
 			)
 			return err
-		} else if err == nil {
-			//unset the finalizers (remove all since we do not expect anything else
-			// to control that object)
-			tmpBootstrapSecret.Finalizers = []string{}
-			err = m.client.Update(ctx, &tmpBootstrapSecret)
-			if err != nil {
-				m.setError("Failed to delete userdata secret",
-					capierrors.DeleteMachineError,
-				)
-				return err
-			}
-			// Delete the secret with use data
-			err = m.client.Delete(ctx, &tmpBootstrapSecret)
-			if err != nil {
-				m.setError("Failed to delete userdata secret",
-					capierrors.DeleteMachineError,
-				)
-				return err
-			}
 		}
 	}
+
 	m.Log.Info("finished deleting bare metal machine")
 	return nil
 }
 
+// deleteSecret deletes a secret created by the machine manager, removing its
+// finalizers first. A secret that does not exist is ignored.
+func (m *MachineManager) deleteSecret(ctx context.Context, key client.ObjectKey) error {
+	tmpSecret := corev1.Secret{}
+	err := m.client.Get(ctx, key, &tmpSecret)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	//unset the finalizers (remove all since we do not expect anything else
+	// to control that object)
+	tmpSecret.Finalizers = []string{}
+	err = m.client.Update(ctx, &tmpSecret)
+	if err != nil {
+		return err
+	}
+	return m.client.Delete(ctx, &tmpSecret)
+}
+
 // Update updates a machine and is invoked by the Machine Controller
 func (m *MachineManager) Update(ctx context.Context) error {
 	m.Log.Info("Updating machine")
@@ -504,6 +662,34 @@ func (m *MachineManager) Update(ctx context.Context) error {
 		return fmt.Errorf("host not found for machine %s", m.Machine.Name)
 	}
 
+	// refresh the user data if the bootstrap data changed
+	err = m.syncUserData(ctx, host)
+	if isInvalidUserDataError(err) {
+		// Do not requeue, the user data or the configuration must be changed
+		m.setError(err.Error(), capierrors.InvalidConfigurationMachineError)
+		return nil
+	} else if err != nil {
+		m.setError("Failed to refresh the UserData of the BareMetalMachine",
+			capierrors.UpdateMachineError,
+		)
+		return err
+	}
+
+	// reprovision the host if the image changed
+	err = m.upgradeImage(ctx, host)
+	if err != nil {
+		if _, ok := errors.Cause(err).(HasRequeueAfterError); ok {
+			return err
+		}
+		m.setError("Failed to upgrade the image of the BareMetalHost",
+			capierrors.UpdateMachineError,
+		)
+		return err
+	}
+
+	// apply the power action requested on the BareMetalMachine, if any
+	m.handlePowerAction(host)
+
 	// ensure that the BMH specs are correctly set
 	err = m.setHostSpec(ctx, host)
 	if err != nil {
@@ -574,24 +760,87 @@ func (m *MachineManager) getHost(ctx context.Context) (*bmh.BareMetalHost, error
 	return &host, nil
 }
 
-// chooseHost iterates through known hosts and returns one that can be
-// associated with the bare metal machine. It searches all hosts in case one already has an
-// association with this bare metal machine.
-func (m *MachineManager) chooseHost(ctx context.Context) (*bmh.BareMetalHost, error) {
-
-	// get list of BMH
-	hosts := bmh.BareMetalHostList{}
-	opts := &client.ListOptions{
-		Namespace: m.Machine.Namespace,
+// getClaimedHost returns the host whose ConsumerRef is the BareMetalMachine,
+// if any.
+func (m *MachineManager) getClaimedHost(ctx context.Context) (*bmh.BareMetalHost, error) {
+	// The configuration may have changed since the host was claimed, so do
+	// not fail on forbidden namespaces, but only look in allowed ones.
+	hostNamespaces := []string{m.Machine.Namespace}
+	for _, namespace := range m.BareMetalMachine.Spec.HostNamespaces {
+		if m.isHostNamespaceAllowed(namespace) {
+			hostNamespaces = append(hostNamespaces, namespace)
+		}
+	}
+	hostRef := m.BareMetalMachine.Spec.HostRef
+	if hostRef != nil && hostRef.Namespace != "" &&
+		m.isHostNamespaceAllowed(hostRef.Namespace) {
+		hostNamespaces = append(hostNamespaces, hostRef.Namespace)
 	}
 
-	err := m.client.List(ctx, &hosts, opts)
+	hosts, err := m.listHosts(ctx, hostNamespaces)
 	if err != nil {
 		return nil, err
 	}
 
-	// Using the label selector on ListOptions above doesn't seem to work.
-	// I think it's because we have a local cache of all BareMetalHosts.
+	for i, host := range hosts.Items {
+		if host.Spec.ConsumerRef != nil &&
+			consumerRefMatches(host.Spec.ConsumerRef, m.BareMetalMachine) {
+			return &hosts.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// hostNamespaces returns the namespaces in which to look for hosts, or an
+// error if one of them is not allowed.
+func (m *MachineManager) hostNamespaces() ([]string, error) {
+	if len(m.BareMetalMachine.Spec.HostNamespaces) == 0 {
+		return []string{m.Machine.Namespace}, nil
+	}
+	for _, namespace := range m.BareMetalMachine.Spec.HostNamespaces {
+		if !m.isHostNamespaceAllowed(namespace) {
+			return nil, errors.Errorf("BareMetalHosts in namespace %s are not allowed for this BareMetalMachine",
+				namespace,
+			)
+		}
+	}
+	return m.BareMetalMachine.Spec.HostNamespaces, nil
+}
+
+// isHostNamespaceAllowed returns true if hosts of the namespace can be
+// claimed by the BareMetalMachine.
+func (m *MachineManager) isHostNamespaceAllowed(namespace string) bool {
+	if namespace == m.Machine.Namespace {
+		return true
+	}
+	for _, allowedNamespace := range m.AllowedHostNamespaces {
+		if namespace == allowedNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// listHosts returns the hosts of all the given namespaces.
+func (m *MachineManager) listHosts(ctx context.Context, namespaces []string) (bmh.BareMetalHostList, error) {
+	hosts := bmh.BareMetalHostList{}
+	for _, namespace := range namespaces {
+		namespaceHosts := bmh.BareMetalHostList{}
+		opts := &client.ListOptions{
+			Namespace: namespace,
+		}
+		err := m.client.List(ctx, &namespaceHosts, opts)
+		if err != nil {
+			return hosts, err
+		}
+		hosts.Items = append(hosts.Items, namespaceHosts.Items...)
+	}
+	return hosts, nil
+}
+
+// hostSelector returns the label selector built from the HostSelector of the
+// BareMetalMachine.
+func (m *MachineManager) hostSelector() (labels.Selector, error) {
 	labelSelector := labels.NewSelector()
 	var reqs labels.Requirements
 
@@ -619,33 +868,276 @@ func (m *MachineManager) chooseHost(ctx context.Context) (*bmh.BareMetalHost, er
 		}
 		reqs = append(reqs, *r)
 	}
-	labelSelector = labelSelector.Add(reqs...)
+	return labelSelector.Add(reqs...), nil
+}
+
+// claimPinnedHost claims the host referenced by the HostRef of the
+// BareMetalMachine. If the host can not be used, the error is set on the
+// BareMetalMachine and nil is returned without error, to not requeue.
+func (m *MachineManager) claimPinnedHost(ctx context.Context) (*bmh.BareMetalHost, error) {
+	hostRef := m.BareMetalMachine.Spec.HostRef
+	namespace := hostRef.Namespace
+	if namespace == "" {
+		namespace = m.Machine.Namespace
+	}
+	if !m.isHostNamespaceAllowed(namespace) {
+		m.setError(fmt.Sprintf("BareMetalHosts in namespace %s are not allowed for this BareMetalMachine",
+			namespace), capierrors.InvalidConfigurationMachineError,
+		)
+		return nil, nil
+	}
+
+	host := bmh.BareMetalHost{}
+	key := client.ObjectKey{
+		Name:      hostRef.Name,
+		Namespace: namespace,
+	}
+	err := m.client.Get(ctx, key, &host)
+	if apierrors.IsNotFound(err) {
+		m.Log.Info("Pinned host not found. Requeuing.", "host", hostRef.Name)
+		return nil, &RequeueAfterError{RequeueAfter: requeueAfter}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if host.Spec.ConsumerRef != nil {
+		if consumerRefMatches(host.Spec.ConsumerRef, m.BareMetalMachine) {
+			m.Log.Info("Found pinned host with existing ConsumerRef", "host", host.Name)
+			return &host, nil
+		}
+		m.setError(fmt.Sprintf("Pinned BareMetalHost %s is already consumed by %s/%s",
+			host.Name, host.Spec.ConsumerRef.Namespace, host.Spec.ConsumerRef.Name,
+		), capierrors.CreateMachineError)
+		return nil, nil
+	}
+
+	labelSelector, err := m.hostSelector()
+	if err != nil {
+		return nil, err
+	}
+	if !labelSelector.Matches(labels.Set(host.ObjectMeta.Labels)) {
+		m.setError(fmt.Sprintf("Pinned BareMetalHost %s does not match the hostSelector",
+			host.Name), capierrors.InvalidConfigurationMachineError,
+		)
+		return nil, nil
+	}
+	err = checkHardwareRequirements(m.BareMetalMachine.Spec.HardwareRequirements,
+		&host,
+	)
+	if err != nil {
+		m.setError(fmt.Sprintf("Pinned BareMetalHost %s does not match the hardwareRequirements: %s",
+			host.Name, err.Error()), capierrors.InvalidConfigurationMachineError,
+		)
+		return nil, nil
+	}
+
+	_, err = m.hostImage(&host)
+	if err != nil {
+		m.setError(fmt.Sprintf("Image can not be rendered for pinned BareMetalHost %s: %s",
+			host.Name, err.Error()), capierrors.InvalidConfigurationMachineError,
+		)
+		return nil, nil
+	}
+
+	// The host may be in error or being deleted
+	if !host.Available() {
+		m.Log.Info("Pinned host not available. Requeuing.", "host", host.Name)
+		return nil, &RequeueAfterError{RequeueAfter: requeueAfter}
+	}
+
+	// A quarantined host is used once an operator releases it
+	_, err = m.releaseQuarantine(ctx, &host)
+	if err != nil {
+		return nil, err
+	}
+	err = checkQuarantine(m.QuarantineThreshold, &host)
+	if err != nil {
+		m.Log.Info("Pinned host quarantined. Requeuing.", "host", host.Name,
+			"reason", err.Error(),
+		)
+		return nil, &RequeueAfterError{RequeueAfter: requeueAfter}
+	}
+
+	// If the host was claimed concurrently, the ConsumerRef will be checked
+	// again after requeuing
+	err = m.claimHost(ctx, &host)
+	if apierrors.IsConflict(err) {
+		m.Log.Info("Pinned host modified concurrently. Requeuing.", "host", host.Name)
+		return nil, &RequeueAfterError{RequeueAfter: requeueAfter}
+	} else if err != nil {
+		return nil, err
+	}
+	return &host, nil
+}
+
+// chooseHost iterates through known hosts and returns one that can be
+// associated with the bare metal machine. It searches all hosts in case one already has an
+// association with this bare metal machine.
+func (m *MachineManager) chooseHost(ctx context.Context,
+	hostNamespaces []string,
+) (*bmh.BareMetalHost, error) {
+
+	// get list of BMH
+	hosts, err := m.listHosts(ctx, hostNamespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	// Using the label selector on ListOptions above doesn't seem to work.
+	// I think it's because we have a local cache of all BareMetalHosts.
+	labelSelector, err := m.hostSelector()
+	if err != nil {
+		return nil, err
+	}
+
+	failureDomainLabel := ""
+	if m.BareMetalCluster != nil {
+		failureDomainLabel = m.BareMetalCluster.Spec.FailureDomainLabel
+	}
+
+	// The failure domain of the hosts is their rack, if set
+	chooser, err := NewHostChooser(m.BareMetalMachine.Spec.HostSelectionStrategy,
+		hosts.Items, m.Machine.Spec.ClusterName, failureDomainLabel,
+	)
+	if err != nil {
+		m.Log.Error(err, "Failed to create the host chooser, not choosing host")
+		return nil, err
+	}
 
 	availableHosts := []*bmh.BareMetalHost{}
+	selection := &capm3.HostSelectionStatus{
+		Strategy:   chooser.Strategy(),
+		Selector:   labelSelector.String(),
+		Considered: len(hosts.Items),
+	}
 
 	for i, host := range hosts.Items {
 		if host.Available() {
-			if labelSelector.Matches(labels.Set(host.ObjectMeta.Labels)) {
-				m.Log.Info("Host matched hostSelector for BareMetalMachine", "host", host.Name)
-				availableHosts = append(availableHosts, &hosts.Items[i])
-			} else {
+			if !labelSelector.Matches(labels.Set(host.ObjectMeta.Labels)) {
 				m.Log.Info("Host did not match hostSelector for BareMetalMachine", "host", host.Name)
+				selection.RejectedBySelector++
+				continue
 			}
+			err = checkHardwareRequirements(m.BareMetalMachine.Spec.HardwareRequirements,
+				&hosts.Items[i],
+			)
+			if err != nil {
+				m.Log.Info("Host did not match hardwareRequirements for BareMetalMachine",
+					"host", host.Name, "reason", err.Error(),
+				)
+				selection.RejectedByFilters++
+				continue
+			}
+			err = checkFailureDomain(failureDomainLabel, m.Machine.Spec.FailureDomain,
+				&hosts.Items[i],
+			)
+			if err != nil {
+				m.Log.Info("Host did not match failure domain for BareMetalMachine",
+					"host", host.Name, "reason", err.Error(),
+				)
+				selection.RejectedByFilters++
+				continue
+			}
+			err = checkQuarantine(m.QuarantineThreshold, &hosts.Items[i])
+			if err != nil {
+				m.Log.Info("Host quarantined, not choosing it for BareMetalMachine",
+					"host", host.Name, "reason", err.Error(),
+				)
+				selection.RejectedByFilters++
+				continue
+			}
+			// The image is rendered for the chosen host once it is claimed
+			_, err = m.hostImage(&hosts.Items[i])
+			if err != nil {
+				m.Log.Info("Image can not be rendered for host, not choosing it for BareMetalMachine",
+					"host", host.Name, "reason", err.Error(),
+				)
+				selection.RejectedByFilters++
+				continue
+			}
+			m.Log.Info("Host matched hostSelector for BareMetalMachine", "host", host.Name)
+			availableHosts = append(availableHosts, &hosts.Items[i])
 		} else if host.Spec.ConsumerRef != nil && consumerRefMatches(host.Spec.ConsumerRef, m.BareMetalMachine) {
 			m.Log.Info("Found host with existing ConsumerRef", "host", host.Name)
 			return &hosts.Items[i], nil
+		} else {
+			selection.NotAvailable++
 		}
 	}
 	m.Log.Info(fmt.Sprintf("%d hosts available while choosing host for bare metal machine", len(availableHosts)))
+
+	// Record why the host was chosen, or why none could be
+	m.BareMetalMachine.Status.HostSelection = selection
 	if len(availableHosts) == 0 {
 		return nil, nil
 	}
 
-	// choose a host at random from available hosts
-	rand.Seed(time.Now().Unix())
-	chosenHost := availableHosts[rand.Intn(len(availableHosts))]
+	// The list of hosts may be outdated, another BareMetalMachine may have
+	// claimed the chosen host in the meantime. In that case, the claim fails
+	// with a conflict and we choose among the remaining hosts.
+	for len(availableHosts) > 0 {
+		chosenHost, score := chooseFrom(chooser, availableHosts)
+		lastUsedHost := m.lastUsedHost(availableHosts)
+		if lastUsedHost != nil {
+			chosenHost, score = lastUsedHost, chooser.Score(lastUsedHost)
+		}
+		err = m.claimHost(ctx, chosenHost)
+		if apierrors.IsConflict(err) {
+			m.Log.Info("Host claimed concurrently, choosing another one",
+				"host", chosenHost.Name,
+			)
+			availableHosts = removeHost(availableHosts, chosenHost)
+			selection.NotAvailable++
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		m.Log.Info("Host chosen for bare metal machine", "host", chosenHost.Name,
+			"strategy", chooser.Strategy(), "score", score,
+			"reused", lastUsedHost != nil,
+		)
+		selection.Score = score
+		selection.Reused = lastUsedHost != nil
+		return chosenHost, nil
+	}
+
+	m.Log.Info("All available hosts were claimed concurrently")
+	return nil, nil
+}
 
-	return chosenHost, nil
+// claimHost sets the ConsumerRef of the host to the BareMetalMachine. This is
+// the first modification of the host when associating it, and the update is
+// guarded by the resourceVersion of the host, so that it fails with a
+// conflict if another BareMetalMachine claimed the host concurrently. Since
+// the host is claimed before any other change is made, a failure later in
+// the association leaves the host consumed by this BareMetalMachine, which
+// will find it again on the next reconciliation.
+func (m *MachineManager) claimHost(ctx context.Context, host *bmh.BareMetalHost) error {
+	host.Spec.ConsumerRef = m.consumerRef()
+	return m.client.Update(ctx, host)
+}
+
+// consumerRef returns a reference to the BareMetalMachine, to be set as
+// ConsumerRef of a host.
+func (m *MachineManager) consumerRef() *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind:       "BareMetalMachine",
+		Name:       m.BareMetalMachine.Name,
+		Namespace:  m.BareMetalMachine.Namespace,
+		APIVersion: m.BareMetalMachine.APIVersion,
+	}
+}
+
+// removeHost returns the list of hosts without the given host.
+func removeHost(hosts []*bmh.BareMetalHost, host *bmh.BareMetalHost) []*bmh.BareMetalHost {
+	remainingHosts := []*bmh.BareMetalHost{}
+	for _, remainingHost := range hosts {
+		if remainingHost != host {
+			remainingHosts = append(remainingHosts, remainingHost)
+		}
+	}
+	return remainingHosts
 }
 
 // consumerRefMatches returns a boolean based on whether the consumer
@@ -718,34 +1210,66 @@ func (m *MachineManager) setHostSpec(ctx context.Context, host *bmh.BareMetalHos
 	// We only want to update the image setting if the host does not
 	// already have an image.
 	//
-	// A host with an existing image is already provisioned and
-	// upgrades are not supported at this time. To re-provision a
-	// host, we must fully deprovision it and then provision it again.
+	// A host with an existing image is already provisioned. To
+	// re-provision a host, we must fully deprovision it and then
+	// provision it again, see upgradeImage.
 	// Not provisioning while we do not have the UserData
-	if host.Spec.Image == nil && m.BareMetalMachine.Spec.UserData != nil {
-		host.Spec.Image = &bmh.Image{
-			URL:      m.BareMetalMachine.Spec.Image.URL,
-			Checksum: m.BareMetalMachine.Spec.Image.Checksum,
+	if host.Spec.Image == nil && m.BareMetalMachine.Spec.UserData != nil &&
+		!m.isDeprovisioningForUpgrade() {
+		image, err := m.hostImage(host)
+		if err != nil {
+			return err
 		}
+		host.Spec.Image = image
 		host.Spec.UserData = m.BareMetalMachine.Spec.UserData
 		if host.Spec.UserData != nil && host.Spec.UserData.Namespace == "" {
 			host.Spec.UserData.Namespace = m.Machine.Namespace
 		}
 	}
 
-	host.Spec.ConsumerRef = &corev1.ObjectReference{
-		Kind:       "BareMetalMachine",
-		Name:       m.BareMetalMachine.Name,
-		Namespace:  m.BareMetalMachine.Namespace,
-		APIVersion: m.BareMetalMachine.APIVersion,
-	}
+	host.Spec.ConsumerRef = m.consumerRef()
 
-	host.Spec.Online = true
-	// Set OwnerReferences
-	host.OwnerReferences = m.SetOwnerRef(host.OwnerReferences, true)
+	// The host is kept powered off while it is power cycled
+	host.Spec.Online = !hostKeptPoweredOff(host)
+	// Set OwnerReferences. Owner references across namespaces are not
+	// allowed, so a host from another namespace is only referenced by its
+	// ConsumerRef.
+	if host.Namespace == m.BareMetalMachine.Namespace {
+		host.OwnerReferences = m.SetOwnerRef(host.OwnerReferences, true)
+	}
 	return m.client.Update(ctx, host)
 }
 
+// hostImage returns the image of the BareMetalMachine for the host, with the
+// templates of the URL and the checksum rendered with the data of the host.
+func (m *MachineManager) hostImage(host *bmh.BareMetalHost) (*bmh.Image, error) {
+	data := capm3.ImageTemplateData{
+		Name:      host.Name,
+		Namespace: host.Namespace,
+		Labels:    host.Labels,
+	}
+	if host.Status.HardwareDetails != nil {
+		data.Arch = host.Status.HardwareDetails.CPU.Arch
+	}
+
+	url, err := capm3.RenderImageTemplate(m.BareMetalMachine.Spec.Image.URL, data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render the image URL for host %s",
+			host.Name,
+		)
+	}
+	checksum, err := capm3.RenderImageTemplate(m.BareMetalMachine.Spec.Image.Checksum, data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render the image checksum for host %s",
+			host.Name,
+		)
+	}
+	return &bmh.Image{
+		URL:      url,
+		Checksum: checksum,
+	}, nil
+}
+
 // ensureAnnotation makes sure the machine has an annotation that references the
 // host and uses the API to update the machine if necessary.
 func (m *MachineManager) ensureAnnotation(ctx context.Context, host *bmh.BareMetalHost) error {
@@ -21,6 +21,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -36,6 +37,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	clientfake "k8s.io/client-go/kubernetes/fake"
 	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/klog/klogr"
@@ -50,6 +52,8 @@ const (
 	testImageURL           = "http://172.22.0.1/images/rhcos-ootpa-latest.qcow2"
 	testImageChecksumURL   = "http://172.22.0.1/images/rhcos-ootpa-latest.qcow2.md5sum"
 	testUserDataSecretName = "worker-user-data"
+	// sha256 of the bootstrap and extra user data of the GetUserData test
+	extraUserDataBoundary = "f3bd2cdcf6711864dd6de80189170e70f0a6685cfcc7225931b293e0679b4e8b"
 )
 
 var ProviderID = "metal3://12345ID6789"
@@ -169,6 +173,12 @@ func bmmObjectMetaWithValidAnnotations() *metav1.ObjectMeta {
 	}
 }
 
+func bmmObjectMetaWithForceRelease() *metav1.ObjectMeta {
+	objectMeta := bmmObjectMetaWithValidAnnotations()
+	objectMeta.Annotations[ForceReleaseAnnotation] = ""
+	return objectMeta
+}
+
 func bmmObjectMetaWithInvalidAnnotations() *metav1.ObjectMeta {
 	return &metav1.ObjectMeta{
 		Name:            "foobarbmmachine",
@@ -225,6 +235,29 @@ func bmhStatus() *bmh.BareMetalHostStatus {
 	}
 }
 
+// conflictingClient is a client returning a conflict when updating the given
+// hosts, as if they had been modified concurrently.
+type conflictingClient struct {
+	client.Client
+	hosts []string
+}
+
+func (c *conflictingClient) Update(ctx context.Context, obj runtime.Object,
+	opts ...client.UpdateOption,
+) error {
+	if host, ok := obj.(*bmh.BareMetalHost); ok {
+		for _, name := range c.hosts {
+			if host.Name == name {
+				return apierrors.NewConflict(
+					schema.GroupResource{Group: "metal3.io", Resource: "baremetalhosts"},
+					host.Name, errors.New("the object has been modified"),
+				)
+			}
+		}
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
 var _ = Describe("BareMetalMachine manager", func() {
 	DescribeTable("Test Finalizers",
 		func(bmMachine capm3.BareMetalMachine) {
@@ -437,10 +470,55 @@ var _ = Describe("BareMetalMachine manager", func() {
 				Labels:    map[string]string{"key1": "value1"},
 			},
 		}
+		hostWithHardware := bmh.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hostWithHardware",
+				Namespace: "myns",
+			},
+			Status: bmh.BareMetalHostStatus{
+				HardwareDetails: &bmh.HardwareDetails{
+					CPU: bmh.CPU{Count: 32},
+				},
+			},
+		}
+
+		quarantinedHost := bmh.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "quarantinedHost",
+				Namespace: "myns",
+				Labels:    map[string]string{HostQuarantineLabel: "true"},
+			},
+		}
+		releasedHost := bmh.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "releasedHost",
+				Namespace: "myns",
+				Labels:    map[string]string{HostQuarantineLabel: "true"},
+				Annotations: map[string]string{
+					HostProvisioningFailuresAnnotation: "3",
+					HostReleaseQuarantineAnnotation:    "",
+				},
+			},
+		}
+
+		lastUsedHost := bmh.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "lastUsedHost",
+				Namespace: "myns",
+				Annotations: map[string]string{
+					HostLastConsumerAnnotation: "myns/machine1",
+				},
+			},
+		}
 
 		bmmconfig, infrastructureRef := newConfig("", map[string]string{},
 			[]capm3.HostSelectorRequirement{},
 		)
+		bmmconfigReuse, infrastructureRefReuse := newConfig("",
+			map[string]string{}, []capm3.HostSelectorRequirement{},
+		)
+		bmmconfigReuse.Spec.ReuseLastHost = true
+		bmmconfigReuse.Spec.HostSelectionStrategy = capm3.HostSelectionBestFit
 		bmmconfig2, infrastructureRef2 := newConfig("",
 			map[string]string{"key1": "value1"}, []capm3.HostSelectorRequirement{},
 		)
@@ -466,22 +544,52 @@ var _ = Describe("BareMetalMachine manager", func() {
 			},
 		)
 
+		bmmconfig6, infrastructureRef6 := newConfig("", map[string]string{},
+			[]capm3.HostSelectorRequirement{},
+		)
+		bmmconfig6.Spec.HardwareRequirements = &capm3.HardwareRequirements{
+			MinCPUCount: 16,
+		}
+
+		bmmconfigImage, infrastructureRefImage := newConfig("", map[string]string{},
+			[]capm3.HostSelectorRequirement{},
+		)
+		bmmconfigImage.Spec.Image.URL = "http://172.22.0.1/images/{{ .Labels.key1 }}.qcow2"
+
 		type testCaseChooseHost struct {
 			Machine          *capi.Machine
 			Hosts            []runtime.Object
 			BMMachine        *capm3.BareMetalMachine
+			ConflictingHosts []string
+			HostNamespaces   []string
 			ExpectedHostName string
+			// Checked if set
+			ExpectedHostSelection *capm3.HostSelectionStatus
 		}
 
 		DescribeTable("Test ChooseHost",
 			func(tc testCaseChooseHost) {
-				c := fakeclient.NewFakeClientWithScheme(setupSchemeMm(), tc.Hosts...)
+				var c client.Client
+				c = fakeclient.NewFakeClientWithScheme(setupSchemeMm(), tc.Hosts...)
+				if len(tc.ConflictingHosts) > 0 {
+					c = &conflictingClient{Client: c, hosts: tc.ConflictingHosts}
+				}
 				machineMgr, err := NewMachineManager(c, nil, nil, tc.Machine,
 					tc.BMMachine, klogr.New(),
 				)
 				Expect(err).NotTo(HaveOccurred())
 
-				result, err := machineMgr.chooseHost(context.TODO())
+				hostNamespaces := tc.HostNamespaces
+				if hostNamespaces == nil {
+					hostNamespaces = []string{tc.Machine.Namespace}
+				}
+				result, err := machineMgr.chooseHost(context.TODO(), hostNamespaces)
+
+				if tc.ExpectedHostSelection != nil {
+					Expect(tc.BMMachine.Status.HostSelection).To(
+						Equal(tc.ExpectedHostSelection),
+					)
+				}
 
 				if tc.ExpectedHostName == "" {
 					Expect(result).To(BeNil())
@@ -490,6 +598,18 @@ var _ = Describe("BareMetalMachine manager", func() {
 				Expect(err).NotTo(HaveOccurred())
 				if result != nil {
 					Expect(result.Name).To(Equal(tc.ExpectedHostName))
+
+					// The chosen host is claimed
+					savedHost := bmh.BareMetalHost{}
+					err = c.Get(context.TODO(), client.ObjectKey{
+						Name:      result.Name,
+						Namespace: result.Namespace,
+					}, &savedHost)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(savedHost.Spec.ConsumerRef).NotTo(BeNil())
+					Expect(savedHost.Spec.ConsumerRef.Name).To(
+						Equal(tc.BMMachine.Name),
+					)
 				}
 			},
 			Entry("Pick host2 which lacks ConsumerRef", testCaseChooseHost{
@@ -536,9 +656,16 @@ var _ = Describe("BareMetalMachine manager", func() {
 			}),
 			Entry("No host that matches required label", testCaseChooseHost{
 				Machine:          newMachine("machine1", "", infrastructureRef3),
-				Hosts:            []runtime.Object{&host2, &hostWithLabel},
+				Hosts:            []runtime.Object{&host2, &hostWithLabel, &host1},
 				BMMachine:        bmmconfig3,
 				ExpectedHostName: "",
+				ExpectedHostSelection: &capm3.HostSelectionStatus{
+					Strategy:           capm3.HostSelectionRandom,
+					Selector:           "boguskey=value",
+					Considered:         3,
+					NotAvailable:       1,
+					RejectedBySelector: 2,
+				},
 			}),
 			Entry("Host that matches a matchExpression", testCaseChooseHost{
 				Machine:          newMachine("machine1", "", infrastructureRef4),
@@ -560,15 +687,306 @@ var _ = Describe("BareMetalMachine manager", func() {
 				BMMachine:        bmmconfig5,
 				ExpectedHostName: "",
 			}),
+			Entry("Choose the host that meets the hardware requirements",
+				testCaseChooseHost{
+					Machine:          newMachine("machine1", "", infrastructureRef6),
+					Hosts:            []runtime.Object{&host2, &hostWithHardware},
+					BMMachine:        bmmconfig6,
+					ExpectedHostName: hostWithHardware.Name,
+				},
+			),
+			Entry("No host that meets the hardware requirements",
+				testCaseChooseHost{
+					Machine:          newMachine("machine1", "", infrastructureRef6),
+					Hosts:            []runtime.Object{&host2, &hostWithLabel},
+					BMMachine:        bmmconfig6,
+					ExpectedHostName: "",
+					ExpectedHostSelection: &capm3.HostSelectionStatus{
+						Strategy:          capm3.HostSelectionRandom,
+						Considered:        2,
+						RejectedByFilters: 2,
+					},
+				},
+			),
+			Entry("Choose the host for which the image can be rendered",
+				testCaseChooseHost{
+					Machine:          newMachine("machine1", "", infrastructureRefImage),
+					Hosts:            []runtime.Object{&host2, &hostWithLabel},
+					BMMachine:        bmmconfigImage,
+					ExpectedHostName: hostWithLabel.Name,
+					ExpectedHostSelection: &capm3.HostSelectionStatus{
+						Strategy:          capm3.HostSelectionRandom,
+						Considered:        2,
+						RejectedByFilters: 1,
+					},
+				},
+			),
+			Entry("No host chosen, the only one is quarantined",
+				testCaseChooseHost{
+					Machine:          newMachine("machine1", "", infrastructureRef),
+					Hosts:            []runtime.Object{&quarantinedHost},
+					BMMachine:        bmmconfig,
+					ExpectedHostName: "",
+					ExpectedHostSelection: &capm3.HostSelectionStatus{
+						Strategy:          capm3.HostSelectionRandom,
+						Considered:        1,
+						RejectedByFilters: 1,
+					},
+				},
+			),
+			Entry("No host chosen, the release of the quarantine is not applied",
+				testCaseChooseHost{
+					Machine:          newMachine("machine1", "", infrastructureRef),
+					Hosts:            []runtime.Object{&quarantinedHost, &releasedHost},
+					BMMachine:        bmmconfig,
+					ExpectedHostName: "",
+					ExpectedHostSelection: &capm3.HostSelectionStatus{
+						Strategy:          capm3.HostSelectionRandom,
+						Considered:        2,
+						RejectedByFilters: 2,
+					},
+				},
+			),
+			Entry("Choose the host last used by the machine",
+				testCaseChooseHost{
+					Machine:          newMachine("machine1", "", infrastructureRefReuse),
+					Hosts:            []runtime.Object{&hostWithHardware, &lastUsedHost},
+					BMMachine:        bmmconfigReuse,
+					ExpectedHostName: lastUsedHost.Name,
+					ExpectedHostSelection: &capm3.HostSelectionStatus{
+						Strategy:   capm3.HostSelectionBestFit,
+						Score:      math.MinInt64,
+						Considered: 2,
+						Reused:     true,
+					},
+				},
+			),
+			Entry("Choose a host in another namespace",
+				testCaseChooseHost{
+					Machine:          newMachine("machine2", "", infrastructureRef),
+					Hosts:            []runtime.Object{&host1, &host3, &host4},
+					BMMachine:        bmmconfig,
+					HostNamespaces:   []string{"myns", "someotherns"},
+					ExpectedHostName: host4.Name,
+				},
+			),
+			Entry("Choose another host if the chosen one was claimed concurrently",
+				testCaseChooseHost{
+					Machine:          newMachine("machine1", "", infrastructureRef),
+					Hosts:            []runtime.Object{&host2, &hostWithLabel},
+					BMMachine:        bmmconfig,
+					ConflictingHosts: []string{host2.Name},
+					ExpectedHostName: hostWithLabel.Name,
+				},
+			),
+			Entry("No host chosen if all were claimed concurrently",
+				testCaseChooseHost{
+					Machine:          newMachine("machine1", "", infrastructureRef),
+					Hosts:            []runtime.Object{&host2, &hostWithLabel},
+					BMMachine:        bmmconfig,
+					ConflictingHosts: []string{host2.Name, hostWithLabel.Name},
+					ExpectedHostName: "",
+					ExpectedHostSelection: &capm3.HostSelectionStatus{
+						Strategy:     capm3.HostSelectionRandom,
+						Considered:   2,
+						NotAvailable: 2,
+					},
+				},
+			),
 		)
 	})
 
+	type testCaseHostNamespaces struct {
+		HostNamespaces         []string
+		AllowedHostNamespaces  []string
+		ExpectedHostNamespaces []string
+		ExpectError            bool
+	}
+
+	DescribeTable("Test hostNamespaces",
+		func(tc testCaseHostNamespaces) {
+			bmmconfig, infrastructureRef := newConfig("", map[string]string{},
+				[]capm3.HostSelectorRequirement{},
+			)
+			bmmconfig.Spec.HostNamespaces = tc.HostNamespaces
+			machine := newMachine("machine1", "", infrastructureRef)
+
+			machineMgr, err := NewMachineManager(nil, nil, nil, machine, bmmconfig,
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			machineMgr.AllowedHostNamespaces = tc.AllowedHostNamespaces
+
+			hostNamespaces, err := machineMgr.hostNamespaces()
+			if tc.ExpectError {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hostNamespaces).To(Equal(tc.ExpectedHostNamespaces))
+		},
+		Entry("Default to the Machine namespace", testCaseHostNamespaces{
+			ExpectedHostNamespaces: []string{"myns"},
+		}),
+		Entry("Machine namespace always allowed", testCaseHostNamespaces{
+			HostNamespaces:         []string{"myns"},
+			ExpectedHostNamespaces: []string{"myns"},
+		}),
+		Entry("Allowed namespaces", testCaseHostNamespaces{
+			HostNamespaces:         []string{"inventory", "myns"},
+			AllowedHostNamespaces:  []string{"inventory"},
+			ExpectedHostNamespaces: []string{"inventory", "myns"},
+		}),
+		Entry("Namespace not allowed", testCaseHostNamespaces{
+			HostNamespaces:        []string{"inventory", "otherinventory"},
+			AllowedHostNamespaces: []string{"inventory"},
+			ExpectError:           true,
+		}),
+	)
+
+	type testCaseClaimPinnedHost struct {
+		HostRef          *corev1.ObjectReference
+		HostSelector     capm3.HostSelector
+		ImageURL         string
+		Hosts            []runtime.Object
+		ExpectedHostName string
+		ExpectRequeue    bool
+		ExpectedFailure  *capierrors.MachineStatusError
+	}
+
+	pinnedHost := func(name string, labels map[string]string,
+		consumer *corev1.ObjectReference,
+	) *bmh.BareMetalHost {
+		return &bmh.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "myns",
+				Labels:    labels,
+			},
+			Spec: bmh.BareMetalHostSpec{
+				ConsumerRef: consumer,
+			},
+		}
+	}
+	invalidConfigurationError := capierrors.InvalidConfigurationMachineError
+	createMachineError := capierrors.CreateMachineError
+
+	DescribeTable("Test claimPinnedHost",
+		func(tc testCaseClaimPinnedHost) {
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm(), tc.Hosts...)
+			bmmconfig, infrastructureRef := newConfig("", map[string]string{},
+				[]capm3.HostSelectorRequirement{},
+			)
+			bmmconfig.TypeMeta = metav1.TypeMeta{
+				Kind:       "BareMetalMachine",
+				APIVersion: capm3.GroupVersion.String(),
+			}
+			bmmconfig.Name = "mybmmachine"
+			bmmconfig.Spec.HostRef = tc.HostRef
+			bmmconfig.Spec.HostSelector = tc.HostSelector
+			if tc.ImageURL != "" {
+				bmmconfig.Spec.Image.URL = tc.ImageURL
+			}
+			machine := newMachine("machine1", "", infrastructureRef)
+
+			machineMgr, err := NewMachineManager(c, nil, nil, machine, bmmconfig,
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			host, err := machineMgr.claimPinnedHost(context.TODO())
+			if tc.ExpectRequeue {
+				_, ok := errors.Cause(err).(HasRequeueAfterError)
+				Expect(ok).To(BeTrue())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(bmmconfig.Status.FailureReason).To(Equal(tc.ExpectedFailure))
+			if tc.ExpectedHostName == "" {
+				Expect(host).To(BeNil())
+				return
+			}
+			Expect(host).NotTo(BeNil())
+			Expect(host.Name).To(Equal(tc.ExpectedHostName))
+
+			savedHost := bmh.BareMetalHost{}
+			err = c.Get(context.TODO(), client.ObjectKey{
+				Name:      host.Name,
+				Namespace: host.Namespace,
+			}, &savedHost)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(savedHost.Spec.ConsumerRef).NotTo(BeNil())
+			Expect(savedHost.Spec.ConsumerRef.Name).To(Equal(bmmconfig.Name))
+		},
+		Entry("Claim the pinned host", testCaseClaimPinnedHost{
+			HostRef:          &corev1.ObjectReference{Name: "host1"},
+			Hosts:            []runtime.Object{pinnedHost("host1", nil, nil)},
+			ExpectedHostName: "host1",
+		}),
+		Entry("Pinned host already claimed by the machine", testCaseClaimPinnedHost{
+			HostRef: &corev1.ObjectReference{Name: "host1"},
+			Hosts: []runtime.Object{pinnedHost("host1", nil,
+				&corev1.ObjectReference{
+					Name:       "mybmmachine",
+					Namespace:  "myns",
+					Kind:       "BareMetalMachine",
+					APIVersion: capm3.GroupVersion.String(),
+				},
+			)},
+			ExpectedHostName: "host1",
+		}),
+		Entry("Pinned host not found", testCaseClaimPinnedHost{
+			HostRef:       &corev1.ObjectReference{Name: "host1"},
+			Hosts:         []runtime.Object{pinnedHost("host2", nil, nil)},
+			ExpectRequeue: true,
+		}),
+		Entry("Pinned host consumed by another machine", testCaseClaimPinnedHost{
+			HostRef: &corev1.ObjectReference{Name: "host1"},
+			Hosts: []runtime.Object{pinnedHost("host1", nil,
+				&corev1.ObjectReference{
+					Name:       "someothermachine",
+					Namespace:  "myns",
+					Kind:       "BareMetalMachine",
+					APIVersion: capm3.GroupVersion.String(),
+				},
+			)},
+			ExpectedFailure: &createMachineError,
+		}),
+		Entry("Pinned host does not match the selector", testCaseClaimPinnedHost{
+			HostRef: &corev1.ObjectReference{Name: "host1"},
+			HostSelector: capm3.HostSelector{
+				MatchLabels: map[string]string{"key1": "value1"},
+			},
+			Hosts: []runtime.Object{pinnedHost("host1",
+				map[string]string{"key1": "value2"}, nil,
+			)},
+			ExpectedFailure: &invalidConfigurationError,
+		}),
+		Entry("Image can not be rendered for the pinned host", testCaseClaimPinnedHost{
+			HostRef:         &corev1.ObjectReference{Name: "host1"},
+			ImageURL:        "http://172.22.0.1/images/{{ .Labels.key1 }}.qcow2",
+			Hosts:           []runtime.Object{pinnedHost("host1", nil, nil)},
+			ExpectedFailure: &invalidConfigurationError,
+		}),
+		Entry("Pinned host in a namespace not allowed", testCaseClaimPinnedHost{
+			HostRef: &corev1.ObjectReference{
+				Name:      "host1",
+				Namespace: "inventory",
+			},
+			Hosts:           []runtime.Object{pinnedHost("host1", nil, nil)},
+			ExpectedFailure: &invalidConfigurationError,
+		}),
+	)
+
 	type testCaseSetHostSpec struct {
 		UserDataNamespace         string
 		ExpectedUserDataNamespace string
 		Host                      *bmh.BareMetalHost
 		ExpectedImage             *bmh.Image
 		ExpectUserData            bool
+		// Replaces the image URL of the BareMetalMachine if set
+		ImageURL         string
+		ExpectPoweredOff bool
 	}
 
 	DescribeTable("Test SetHostSpec",
@@ -578,6 +996,9 @@ var _ = Describe("BareMetalMachine manager", func() {
 			bmmconfig, infrastructureRef := newConfig(tc.UserDataNamespace,
 				map[string]string{}, []capm3.HostSelectorRequirement{},
 			)
+			if tc.ImageURL != "" {
+				bmmconfig.Spec.Image.URL = tc.ImageURL
+			}
 			machine := newMachine("machine1", "", infrastructureRef)
 
 			machineMgr, err := NewMachineManager(c, nil, nil, machine, bmmconfig,
@@ -605,7 +1026,7 @@ var _ = Describe("BareMetalMachine manager", func() {
 			Expect(savedHost.Spec.ConsumerRef.Namespace).
 				To(Equal(bmmconfig.Namespace))
 			Expect(savedHost.Spec.ConsumerRef.Kind).To(Equal("BareMetalMachine"))
-			Expect(savedHost.Spec.Online).To(BeTrue())
+			Expect(savedHost.Spec.Online).To(Equal(!tc.ExpectPoweredOff))
 			if tc.ExpectedImage == nil {
 				Expect(savedHost.Spec.Image).To(BeNil())
 			} else {
@@ -660,6 +1081,56 @@ var _ = Describe("BareMetalMachine manager", func() {
 				ExpectUserData: false,
 			},
 		),
+		Entry("Image URL rendered for the host", testCaseSetHostSpec{
+			UserDataNamespace:         "",
+			ExpectedUserDataNamespace: "myns",
+			Host: &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "host2",
+					Namespace: "myns",
+					Labels:    map[string]string{"os": "rhcos"},
+				},
+				Status: bmh.BareMetalHostStatus{
+					HardwareDetails: &bmh.HardwareDetails{
+						CPU: bmh.CPU{Arch: "aarch64"},
+					},
+				},
+			},
+			ImageURL: "http://172.22.0.1/images/{{ .Labels.os }}-{{ .Arch }}.qcow2",
+			ExpectedImage: &bmh.Image{
+				URL:      "http://172.22.0.1/images/rhcos-aarch64.qcow2",
+				Checksum: testImageChecksumURL,
+			},
+			ExpectUserData: true,
+		}),
+		Entry("Host kept powered off", testCaseSetHostSpec{
+			UserDataNamespace:         "",
+			ExpectedUserDataNamespace: "myns",
+			Host: &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "host2",
+					Namespace:   "myns",
+					Annotations: map[string]string{HostPowerOffAnnotation: ""},
+				},
+			},
+			ExpectedImage:    expectedImg(),
+			ExpectUserData:   true,
+			ExpectPoweredOff: true,
+		}),
+		Entry("Host kept powered off by a remediation", testCaseSetHostSpec{
+			UserDataNamespace:         "",
+			ExpectedUserDataNamespace: "myns",
+			Host: &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "host2",
+					Namespace:   "myns",
+					Annotations: map[string]string{RemediationPowerOffAnnotation: ""},
+				},
+			},
+			ExpectedImage:    expectedImg(),
+			ExpectUserData:   true,
+			ExpectPoweredOff: true,
+		}),
 	)
 
 	Describe("Test Exists function", func() {
@@ -1019,6 +1490,8 @@ var _ = Describe("BareMetalMachine manager", func() {
 		ExpectedResult            error
 		ExpectSecretDeleted       bool
 		ExpectClusterLabelDeleted bool
+		// Checked if set
+		ExpectedLastConsumer string
 	}
 
 	DescribeTable("Test Delete function",
@@ -1071,6 +1544,12 @@ var _ = Describe("BareMetalMachine manager", func() {
 					expectedName = tc.ExpectedConsumerRef.Name
 				}
 				Expect(name).To(Equal(expectedName))
+
+				if tc.ExpectedLastConsumer != "" {
+					Expect(host.Annotations[HostLastConsumerAnnotation]).To(
+						Equal(tc.ExpectedLastConsumer),
+					)
+				}
 			}
 
 			tmpBootstrapSecret := corev1.Secret{}
@@ -1161,6 +1640,18 @@ var _ = Describe("BareMetalMachine manager", func() {
 			ExpectedResult:      &RequeueAfterError{RequeueAfter: time.Second * 30},
 			Secret:              newSecret(),
 		}),
+		Entry("Deprovisioning in progress, host force released", testCaseDelete{
+			Host: newBareMetalHost("myhost", bmhSpecNoImg(),
+				bmh.StateDeprovisioning, bmhStatus(), false, false,
+			),
+			Machine: newMachine("mymachine", "", nil),
+			BMMachine: newBareMetalMachine("mybmmachine", nil, bmmSecret(), nil,
+				bmmObjectMetaWithForceRelease(),
+			),
+			Secret:               newSecret(),
+			ExpectSecretDeleted:  true,
+			ExpectedLastConsumer: "myns/mymachine",
+		}),
 		Entry("Externally provisioned host should be powered down", testCaseDelete{
 			Host: newBareMetalHost("myhost", bmhSpecNoImg(),
 				bmh.StateExternallyProvisioned, bmhPowerStatus(), true, false,
@@ -1194,8 +1685,9 @@ var _ = Describe("BareMetalMachine manager", func() {
 			BMMachine: newBareMetalMachine("mybmmachine", nil, bmmSecret(), nil,
 				bmmObjectMetaWithValidAnnotations(),
 			),
-			Secret:              newSecret(),
-			ExpectSecretDeleted: true,
+			Secret:               newSecret(),
+			ExpectSecretDeleted:  true,
+			ExpectedLastConsumer: "myns/mymachine",
 		}),
 		Entry("Consumer ref should be removed", testCaseDelete{
 			Host: newBareMetalHost("myhost", bmhSpecNoImg(), bmh.StateReady,
@@ -1266,7 +1758,7 @@ var _ = Describe("BareMetalMachine manager", func() {
 				bmmObjectMetaWithValidAnnotations(),
 			),
 			Secret:              newSecret(),
-			ExpectSecretDeleted: false,
+			ExpectSecretDeleted: true,
 		}),
 		Entry("Clusterlabel should be removed", testCaseDelete{
 			Machine:                   newMachine("mymachine", "mybmmachine", nil),
@@ -1679,6 +2171,11 @@ var _ = Describe("BareMetalMachine manager", func() {
 		BMHost      *bmh.BareMetalHost
 		Secret      *corev1.Secret
 		ExpectError bool
+		ExtraSecret *corev1.Secret
+		// Label of the created secret
+		ExpectedFormat string
+		// Defaults to FooBar\n
+		ExpectedUserData string
 	}
 
 	DescribeTable("Test GetUserData function",
@@ -1690,6 +2187,9 @@ var _ = Describe("BareMetalMachine manager", func() {
 			if tc.Secret != nil {
 				objects = append(objects, tc.Secret)
 			}
+			if tc.ExtraSecret != nil {
+				objects = append(objects, tc.ExtraSecret)
+			}
 			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm(), objects...)
 
 			machineMgr, err := NewMachineManager(c, nil, nil, tc.Machine,
@@ -1705,23 +2205,9 @@ var _ = Describe("BareMetalMachine manager", func() {
 				Expect(err).NotTo(HaveOccurred())
 			}
 
-			// Expect the reference to the secret to be passed through
-			if tc.Machine.Spec.Bootstrap.DataSecretName != nil &&
-				tc.Machine.Namespace == tc.BMHost.Namespace {
-				Expect(tc.BMMachine.Spec.UserData.Name).To(Equal(
-					*tc.Machine.Spec.Bootstrap.DataSecretName,
-				))
-				Expect(tc.BMMachine.Spec.UserData.Namespace).To(Equal(
-					tc.BMHost.Namespace,
-				))
-			}
-
-			// if we had to create an additional secret (BMH and BMM in different
-			// namespaces or dataSecretName not set and Data sets)
-			if (tc.Machine.Spec.Bootstrap.DataSecretName == nil &&
-				tc.Machine.Spec.Bootstrap.Data != nil) ||
-				(tc.Machine.Spec.Bootstrap.DataSecretName != nil &&
-					tc.Machine.Namespace != tc.BMHost.Namespace) {
+			// the secret is always created if there is bootstrap data
+			if tc.Machine.Spec.Bootstrap.DataSecretName != nil ||
+				tc.Machine.Spec.Bootstrap.Data != nil {
 
 				Expect(tc.BMMachine.Spec.UserData.Name).To(Equal(
 					tc.BMMachine.Name + "-user-data",
@@ -1736,7 +2222,13 @@ var _ = Describe("BareMetalMachine manager", func() {
 				}
 				err = c.Get(context.TODO(), key, &tmpBootstrapSecret)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(string(tmpBootstrapSecret.Data["userData"])).To(Equal("FooBar\n"))
+				expectedUserData := tc.ExpectedUserData
+				if expectedUserData == "" {
+					expectedUserData = "FooBar\n"
+				}
+				Expect(string(tmpBootstrapSecret.Data["userData"])).To(
+					Equal(expectedUserData),
+				)
 				Expect(len(tmpBootstrapSecret.OwnerReferences)).To(BeEquivalentTo(1))
 				Expect(tmpBootstrapSecret.OwnerReferences[0].APIVersion).
 					To(Equal(tc.BMMachine.APIVersion))
@@ -1750,6 +2242,9 @@ var _ = Describe("BareMetalMachine manager", func() {
 					To(BeTrue())
 				Expect(len(tmpBootstrapSecret.Finalizers)).To(Equal(1))
 				Expect(tmpBootstrapSecret.Finalizers).To(ContainElement(userDataFinalizer))
+				Expect(tmpBootstrapSecret.Labels[BootstrapFormatLabel]).To(
+					Equal(tc.ExpectedFormat),
+				)
 			}
 		},
 		Entry("Secret set in Machine", testCaseGetUserData{
@@ -1808,6 +2303,234 @@ var _ = Describe("BareMetalMachine manager", func() {
 			BMMachine: newBareMetalMachine("mybmmachine", nil, nil, nil, nil),
 			BMHost:    newBareMetalHost("myhost", nil, bmh.StateNone, nil, false, false),
 		}),
+		Entry("Secret set in Machine, custom key", testCaseGetUserData{
+			Secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "Foobar",
+					Namespace: "myns",
+				},
+				Data: map[string][]byte{
+					"userData": []byte("FooBar\n"),
+					"format":   []byte("ignition"),
+				},
+				Type: "Opaque",
+			},
+			Machine: &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "myns",
+				},
+				Spec: capi.MachineSpec{
+					Bootstrap: capi.Bootstrap{
+						DataSecretName: pointer.StringPtr("Foobar"),
+					},
+				},
+			},
+			BMMachine: &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: "myns",
+				},
+				Spec: capm3.BareMetalMachineSpec{
+					BootstrapSecretKey: "userData",
+				},
+			},
+			BMHost:         newBareMetalHost("myhost", nil, bmh.StateNone, nil, false, false),
+			ExpectedFormat: "ignition",
+		}),
+		Entry("Secret set in Machine, kubeadm bootstrap data", testCaseGetUserData{
+			Secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "Foobar",
+					Namespace: "myns",
+				},
+				Data: map[string][]byte{
+					"value": []byte(cabpkBootstrapData),
+				},
+				Type: "Opaque",
+			},
+			Machine: &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "myns",
+				},
+				Spec: capi.MachineSpec{
+					Bootstrap: capi.Bootstrap{
+						DataSecretName: pointer.StringPtr("Foobar"),
+					},
+				},
+			},
+			BMMachine:        newBareMetalMachine("mybmmachine", nil, nil, nil, nil),
+			BMHost:           newBareMetalHost("myhost", nil, bmh.StateNone, nil, false, false),
+			ExpectedFormat:   "cloud-config",
+			ExpectedUserData: cabpkBootstrapData,
+		}),
+		Entry("Secret set in Machine, unknown format", testCaseGetUserData{
+			Secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "Foobar",
+					Namespace: "myns",
+				},
+				Data: map[string][]byte{
+					"value":  []byte("#cloud-config\nruncmd: []\n"),
+					"format": []byte("cloud config/v2!"),
+				},
+				Type: "Opaque",
+			},
+			Machine: &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "myns",
+				},
+				Spec: capi.MachineSpec{
+					Bootstrap: capi.Bootstrap{
+						DataSecretName: pointer.StringPtr("Foobar"),
+					},
+				},
+			},
+			BMMachine:        newBareMetalMachine("mybmmachine", nil, nil, nil, nil),
+			BMHost:           newBareMetalHost("myhost", nil, bmh.StateNone, nil, false, false),
+			ExpectedFormat:   "cloud-config",
+			ExpectedUserData: "#cloud-config\nruncmd: []\n",
+		}),
+		Entry("Secret set in Machine, extra user data", testCaseGetUserData{
+			Secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "Foobar",
+					Namespace: "myns",
+				},
+				Data: map[string][]byte{
+					"value": []byte("#cloud-config\nruncmd: []\n"),
+				},
+				Type: "Opaque",
+			},
+			ExtraSecret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ssh-keys",
+					Namespace: "myns",
+				},
+				Data: map[string][]byte{
+					"cloud-config": []byte("#cloud-config\nssh_authorized_keys: []\n"),
+				},
+				Type: "Opaque",
+			},
+			Machine: &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "myns",
+				},
+				Spec: capi.MachineSpec{
+					Bootstrap: capi.Bootstrap{
+						DataSecretName: pointer.StringPtr("Foobar"),
+					},
+				},
+			},
+			BMMachine: &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: "myns",
+				},
+				Spec: capm3.BareMetalMachineSpec{
+					ExtraUserData: []corev1.SecretKeySelector{
+						corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "ssh-keys",
+							},
+							Key: "cloud-config",
+						},
+						corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "ntp",
+							},
+							Key:      "cloud-config",
+							Optional: pointer.BoolPtr(true),
+						},
+					},
+				},
+			},
+			BMHost:         newBareMetalHost("myhost", nil, bmh.StateNone, nil, false, false),
+			ExpectedFormat: "cloud-config",
+			ExpectedUserData: "Content-Type: multipart/mixed; " +
+				"boundary=\"" + extraUserDataBoundary + "\"\r\n" +
+				"MIME-Version: 1.0\r\n\r\n" +
+				"--" + extraUserDataBoundary + "\r\n" +
+				"Content-Type: text/cloud-config; charset=\"utf-8\"\r\n\r\n" +
+				"#cloud-config\nruncmd: []\n\r\n" +
+				"--" + extraUserDataBoundary + "\r\n" +
+				"Content-Type: text/cloud-config; charset=\"utf-8\"\r\n" +
+				"Merge-Type: " + extraUserDataMergeType + "\r\n\r\n" +
+				"#cloud-config\nssh_authorized_keys: []\n\r\n" +
+				"--" + extraUserDataBoundary + "--\r\n",
+		}),
+		Entry("Secret set in Machine, extra user data missing", testCaseGetUserData{
+			Secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "Foobar",
+					Namespace: "myns",
+				},
+				Data: map[string][]byte{
+					"value": []byte("FooBar\n"),
+				},
+				Type: "Opaque",
+			},
+			Machine: &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "myns",
+				},
+				Spec: capi.MachineSpec{
+					Bootstrap: capi.Bootstrap{
+						DataSecretName: pointer.StringPtr("Foobar"),
+					},
+				},
+			},
+			BMMachine: &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: "myns",
+				},
+				Spec: capm3.BareMetalMachineSpec{
+					ExtraUserData: []corev1.SecretKeySelector{
+						corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "ssh-keys",
+							},
+							Key: "cloud-config",
+						},
+					},
+				},
+			},
+			BMHost:      newBareMetalHost("myhost", nil, bmh.StateNone, nil, false, false),
+			ExpectError: true,
+		}),
+		Entry("Secret set in Machine, custom key missing", testCaseGetUserData{
+			Secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "Foobar",
+					Namespace: "myns",
+				},
+				Data: map[string][]byte{
+					"value": []byte("FooBar\n"),
+				},
+				Type: "Opaque",
+			},
+			Machine: &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "myns",
+				},
+				Spec: capi.MachineSpec{
+					Bootstrap: capi.Bootstrap{
+						DataSecretName: pointer.StringPtr("Foobar"),
+					},
+				},
+			},
+			BMMachine: &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: "myns",
+				},
+				Spec: capm3.BareMetalMachineSpec{
+					BootstrapSecretKey: "userData",
+				},
+			},
+			BMHost:      newBareMetalHost("myhost", nil, bmh.StateNone, nil, false, false),
+			ExpectError: true,
+		}),
 		Entry("Userdata set in Machine, secret exists", testCaseGetUserData{
 			Secret: newSecret(),
 			Machine: &capi.Machine{
@@ -1961,9 +2684,10 @@ var _ = Describe("BareMetalMachine manager", func() {
 				BMMachine: newBareMetalMachine("mybmmachine", nil, bmmSpecAll(), nil,
 					bmmObjectMetaWithValidAnnotations(),
 				),
-				Host:           newBareMetalHost("", nil, bmh.StateNone, nil, false, false),
-				ExpectRequeue:  false,
-				ExpectOwnerRef: true,
+				Host:          newBareMetalHost("", nil, bmh.StateNone, nil, false, false),
+				ExpectRequeue: false,
+				// The host is not in the namespace of the BareMetalMachine
+				ExpectOwnerRef: false,
 			},
 		),
 		Entry("Associate machine, host nil, baremetal machine spec set, requeue",
@@ -2256,6 +2980,9 @@ func newConfig(UserDataNamespace string,
 	labels map[string]string, reqs []capm3.HostSelectorRequirement,
 ) (*capm3.BareMetalMachine, *corev1.ObjectReference) {
 	config := capm3.BareMetalMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "myns",
+		},
 		Spec: capm3.BareMetalMachineSpec{
 			Image: capm3.Image{
 				URL:      testImageURL,
@@ -0,0 +1,340 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/metal3-io/cluster-api-provider-baremetal/baremetal/remote"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RemediationPowerOffAnnotation is the annotation set on a BareMetalHost
+	// to keep it powered off while it is power cycled by a
+	// BareMetalRemediation. It is distinct from the HostPowerOffAnnotation of
+	// the power actions of the BareMetalMachine, so that neither powers the
+	// host on while the other keeps it off.
+	RemediationPowerOffAnnotation = "metal3.io/remediation-power-off"
+
+	defaultRemediationRetryLimit = 1
+	defaultRemediationTimeout    = 10 * time.Minute
+)
+
+// RemediationManagerInterface is an interface for a RemediationManager
+type RemediationManagerInterface interface {
+	SetFinalizer()
+	UnsetFinalizer()
+	IsFinished() bool
+	Remediate(context.Context) error
+	Delete(context.Context) error
+}
+
+// RemediationManager is responsible for performing the remediation of an
+// unhealthy Machine
+type RemediationManager struct {
+	client client.Client
+
+	Remediation *capm3.BareMetalRemediation
+	Machine     *capi.Machine
+	Log         logr.Logger
+
+	// RemoteClientGetter returns the client of the workload cluster, used
+	// to check the Node of the Machine. Defaults to remote.NewClusterClient.
+	RemoteClientGetter ClientGetter
+}
+
+// NewRemediationManager returns a new helper for remediating a machine
+func NewRemediationManager(client client.Client,
+	remediation *capm3.BareMetalRemediation, machine *capi.Machine,
+	remediationLog logr.Logger) (*RemediationManager, error) {
+
+	return &RemediationManager{
+		client: client,
+
+		Remediation: remediation,
+		Machine:     machine,
+		Log:         remediationLog,
+	}, nil
+}
+
+// SetFinalizer sets finalizer
+func (r *RemediationManager) SetFinalizer() {
+	// If the BareMetalRemediation doesn't have finalizer, add it.
+	if !util.Contains(r.Remediation.Finalizers, capm3.RemediationFinalizer) {
+		r.Remediation.Finalizers = append(r.Remediation.Finalizers,
+			capm3.RemediationFinalizer,
+		)
+	}
+}
+
+// UnsetFinalizer unsets finalizer
+func (r *RemediationManager) UnsetFinalizer() {
+	// Remediation is deleted so remove the finalizer.
+	r.Remediation.Finalizers = util.Filter(r.Remediation.Finalizers,
+		capm3.RemediationFinalizer,
+	)
+}
+
+// IsFinished returns whether the remediation succeeded or failed
+func (r *RemediationManager) IsFinished() bool {
+	phase := r.Remediation.Status.Phase
+	return phase == capm3.PhaseSucceeded || phase == capm3.PhaseFailed
+}
+
+// retryLimit returns the number of power cycles to attempt
+func (r *RemediationManager) retryLimit() int {
+	strategy := r.Remediation.Spec.Strategy
+	if strategy == nil || strategy.RetryLimit < 1 {
+		return defaultRemediationRetryLimit
+	}
+	return strategy.RetryLimit
+}
+
+// timeout returns the time given to each power cycle for the Node to be ready
+func (r *RemediationManager) timeout() time.Duration {
+	strategy := r.Remediation.Spec.Strategy
+	if strategy == nil || strategy.Timeout == nil {
+		return defaultRemediationTimeout
+	}
+	return strategy.Timeout.Duration
+}
+
+// Remediate power cycles the BareMetalHost of the Machine until its Node is
+// ready again, up to the retry limit, and then deletes the Machine. A
+// RequeueAfterError is returned while a power cycle is in progress.
+func (r *RemediationManager) Remediate(ctx context.Context) error {
+	if r.IsFinished() {
+		return nil
+	}
+
+	host, err := r.getHost(ctx)
+	if err != nil {
+		return err
+	}
+	if host == nil {
+		r.Log.Info("No BareMetalHost to power cycle, deleting the Machine")
+		return r.deleteMachine(ctx)
+	}
+
+	status := &r.Remediation.Status
+	if status.Phase == "" {
+		r.startAttempt(host)
+	}
+	attempt := &status.Attempts[len(status.Attempts)-1]
+
+	if time.Since(attempt.StartedAt.Time) > r.timeout() {
+		r.Log.Info("Power cycle timed out", "host", host.Name,
+			"attempt", status.RetryCount,
+		)
+		attempt.TimedOut = true
+		attempt.CompletedAt = &metav1.Time{Time: time.Now()}
+		// Leave the host powered on for the next attempt, or the deletion
+		if err := r.setHostPowerOff(ctx, host, false); err != nil {
+			return err
+		}
+		if status.RetryCount >= r.retryLimit() {
+			r.Log.Info("Retry limit reached, deleting the Machine",
+				"retries", status.RetryCount,
+			)
+			return r.deleteMachine(ctx)
+		}
+		r.startAttempt(host)
+		attempt = &status.Attempts[len(status.Attempts)-1]
+	}
+
+	if status.Phase == capm3.PhaseRunning {
+		if attempt.PoweredOffAt == nil {
+			if host.Status.PoweredOn {
+				if err := r.setHostPowerOff(ctx, host, true); err != nil {
+					return err
+				}
+				r.Log.Info("Powering off host, requeuing", "host", host.Name)
+				return &RequeueAfterError{RequeueAfter: requeueAfter}
+			}
+			attempt.PoweredOffAt = &metav1.Time{Time: time.Now()}
+		}
+
+		if err := r.setHostPowerOff(ctx, host, false); err != nil {
+			return err
+		}
+		if !host.Status.PoweredOn {
+			r.Log.Info("Powering on host, requeuing", "host", host.Name)
+			return &RequeueAfterError{RequeueAfter: requeueAfter}
+		}
+		attempt.PoweredOnAt = &metav1.Time{Time: time.Now()}
+		status.Phase = capm3.PhaseWaiting
+	}
+
+	ready, err := r.nodeReady(ctx, attempt.PoweredOnAt.Time)
+	if err != nil {
+		r.Log.Info(fmt.Sprintf("error while checking the node: %v", err))
+		return &RequeueAfterError{RequeueAfter: requeueAfter}
+	}
+	if !ready {
+		r.Log.Info("Waiting for the node to be ready, requeuing")
+		return &RequeueAfterError{RequeueAfter: requeueAfter}
+	}
+
+	r.Log.Info("Node ready after power cycle, remediation succeeded",
+		"host", host.Name,
+	)
+	now := metav1.Now()
+	attempt.CompletedAt = &now
+	status.Phase = capm3.PhaseSucceeded
+	status.LastRemediated = &now
+	return nil
+}
+
+// Delete stops the power cycle of the BareMetalHost, if any, so that a
+// BareMetalRemediation deleted while the host is powered off does not keep
+// it powered off.
+func (r *RemediationManager) Delete(ctx context.Context) error {
+	host, err := r.getHost(ctx)
+	if err != nil {
+		return err
+	}
+	if host == nil {
+		return nil
+	}
+	return r.setHostPowerOff(ctx, host, false)
+}
+
+// startAttempt starts a new power cycle of the host
+func (r *RemediationManager) startAttempt(host *bmh.BareMetalHost) {
+	status := &r.Remediation.Status
+	status.RetryCount++
+	status.Phase = capm3.PhaseRunning
+	status.Attempts = append(status.Attempts, capm3.RemediationAttempt{
+		StartedAt: metav1.Now(),
+	})
+	r.Log.Info("Power cycling host", "host", host.Name,
+		"attempt", status.RetryCount,
+	)
+}
+
+// getHost returns the BareMetalHost of the Machine, found through the
+// annotation of its BareMetalMachine, or nil if there is none.
+func (r *RemediationManager) getHost(ctx context.Context) (*bmh.BareMetalHost, error) {
+	infraRef := r.Machine.Spec.InfrastructureRef
+	bmMachine := &capm3.BareMetalMachine{}
+	key := client.ObjectKey{
+		Name:      infraRef.Name,
+		Namespace: r.Machine.Namespace,
+	}
+	err := r.client.Get(ctx, key, bmMachine)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to get the BareMetalMachine")
+	}
+
+	machineMgr, err := NewMachineManager(r.client, nil, nil, r.Machine,
+		bmMachine, r.Log,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return machineMgr.getHost(ctx)
+}
+
+// setHostPowerOff requests the host to be powered off, or on, if not
+// already done. The host is not powered on while a power off is requested on
+// its BareMetalMachine.
+func (r *RemediationManager) setHostPowerOff(ctx context.Context,
+	host *bmh.BareMetalHost, powerOff bool,
+) error {
+	_, requested := host.Annotations[RemediationPowerOffAnnotation]
+	if requested == powerOff && host.Spec.Online == !hostKeptPoweredOff(host) {
+		return nil
+	}
+	if powerOff {
+		if host.Annotations == nil {
+			host.Annotations = make(map[string]string)
+		}
+		host.Annotations[RemediationPowerOffAnnotation] = ""
+	} else {
+		delete(host.Annotations, RemediationPowerOffAnnotation)
+	}
+	host.Spec.Online = !hostKeptPoweredOff(host)
+	if !powerOff && !host.Spec.Online {
+		r.Log.Info("Host kept powered off by its BareMetalMachine",
+			"host", host.Name,
+		)
+	}
+	if err := r.client.Update(ctx, host); err != nil {
+		return errors.Wrap(err, "failed to set the power state of the host")
+	}
+	return nil
+}
+
+// nodeReady returns whether the Node of the Machine reported it is ready
+// since the given time.
+func (r *RemediationManager) nodeReady(ctx context.Context, since time.Time) (bool, error) {
+	if r.Machine.Status.NodeRef == nil {
+		return false, nil
+	}
+	cluster, err := util.GetClusterFromMetadata(ctx, r.client, r.Machine.ObjectMeta)
+	if err != nil {
+		return false, err
+	}
+
+	clientGetter := r.RemoteClientGetter
+	if clientGetter == nil {
+		clientGetter = remote.NewClusterClient
+	}
+	corev1Remote, err := clientGetter(ctx, r.client, cluster)
+	if err != nil {
+		return false, err
+	}
+	node, err := corev1Remote.Nodes().Get(r.Machine.Status.NodeRef.Name,
+		metav1.GetOptions{},
+	)
+	if err != nil {
+		return false, err
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue &&
+				condition.LastHeartbeatTime.After(since), nil
+		}
+	}
+	return false, nil
+}
+
+// deleteMachine deletes the Machine, the last resort of the remediation
+func (r *RemediationManager) deleteMachine(ctx context.Context) error {
+	err := r.client.Delete(ctx, r.Machine)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete the Machine")
+	}
+	now := metav1.Now()
+	r.Remediation.Status.Phase = capm3.PhaseFailed
+	r.Remediation.Status.LastRemediated = &now
+	return nil
+}
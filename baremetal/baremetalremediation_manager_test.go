@@ -0,0 +1,428 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientfake "k8s.io/client-go/kubernetes/fake"
+	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/klogr"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func remediationAttempt(startedAgo time.Duration, poweredOff, poweredOn bool) capm3.RemediationAttempt {
+	startedAt := time.Now().Add(-startedAgo)
+	attempt := capm3.RemediationAttempt{
+		StartedAt: metav1.Time{Time: startedAt},
+	}
+	if poweredOff {
+		attempt.PoweredOffAt = &metav1.Time{Time: startedAt}
+	}
+	if poweredOn {
+		attempt.PoweredOnAt = &metav1.Time{Time: startedAt}
+	}
+	return attempt
+}
+
+var _ = Describe("BareMetalRemediation manager", func() {
+
+	type testCaseRemediate struct {
+		Strategy      *capm3.RemediationStrategy
+		Status        capm3.BareMetalRemediationStatus
+		NoHost        bool
+		HostPoweredOn bool
+		HostPowerOff  bool
+		NodeReady     bool
+		// PowerOffRequested sets the HostPowerOffAnnotation of a power off
+		// requested on the BareMetalMachine
+		PowerOffRequested bool
+
+		ExpectRequeue        bool
+		ExpectedPhase        capm3.RemediationPhase
+		ExpectedRetryCount   int
+		ExpectHostPowerOff   bool
+		ExpectMachineDeleted bool
+	}
+
+	DescribeTable("Test Remediate",
+		func(tc testCaseRemediate) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "myhost",
+					Namespace: namespaceName,
+				},
+				Spec: bmh.BareMetalHostSpec{
+					Online: !tc.HostPowerOff,
+				},
+				Status: bmh.BareMetalHostStatus{
+					PoweredOn: tc.HostPoweredOn,
+				},
+			}
+			if tc.HostPowerOff {
+				host.Annotations = map[string]string{RemediationPowerOffAnnotation: ""}
+			}
+			if tc.PowerOffRequested {
+				if host.Annotations == nil {
+					host.Annotations = map[string]string{}
+				}
+				host.Annotations[HostPowerOffAnnotation] = ""
+				host.Spec.Online = false
+			}
+			bmMachine := &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: namespaceName,
+				},
+			}
+			if !tc.NoHost {
+				bmMachine.Annotations = map[string]string{
+					HostAnnotation: namespaceName + "/myhost",
+				}
+			}
+			machine := &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mymachine",
+					Namespace: namespaceName,
+					Labels:    map[string]string{capi.ClusterLabelName: clusterName},
+				},
+				Spec: capi.MachineSpec{
+					InfrastructureRef: corev1.ObjectReference{
+						Name: bmMachine.Name,
+					},
+				},
+				Status: capi.MachineStatus{
+					NodeRef: &corev1.ObjectReference{Name: "node-0"},
+				},
+			}
+			remediation := &capm3.BareMetalRemediation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mymachine",
+					Namespace: namespaceName,
+				},
+				Spec: capm3.BareMetalRemediationSpec{
+					Strategy: tc.Strategy,
+				},
+				Status: tc.Status,
+			}
+
+			heartbeat := time.Now().Add(-24 * time.Hour)
+			if tc.NodeReady {
+				heartbeat = time.Now()
+			}
+			clientset := clientfake.NewSimpleClientset(&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+				Status: corev1.NodeStatus{
+					Conditions: []corev1.NodeCondition{
+						corev1.NodeCondition{
+							Type:              corev1.NodeReady,
+							Status:            corev1.ConditionTrue,
+							LastHeartbeatTime: metav1.Time{Time: heartbeat},
+						},
+					},
+				},
+			})
+
+			objects := []runtime.Object{host, bmMachine, machine,
+				newCluster(clusterName),
+			}
+			c := fakeclient.NewFakeClientWithScheme(setupScheme(), objects...)
+			remediationMgr, err := NewRemediationManager(c, remediation, machine,
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			remediationMgr.RemoteClientGetter = func(ctx context.Context,
+				c client.Client, cluster *capi.Cluster,
+			) (clientcorev1.CoreV1Interface, error) {
+				return clientset.CoreV1(), nil
+			}
+
+			err = remediationMgr.Remediate(context.TODO())
+			if tc.ExpectRequeue {
+				_, ok := errors.Cause(err).(HasRequeueAfterError)
+				Expect(ok).To(BeTrue())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			status := remediation.Status
+			Expect(status.Phase).To(Equal(tc.ExpectedPhase))
+			Expect(status.RetryCount).To(Equal(tc.ExpectedRetryCount))
+			Expect(status.Attempts).To(HaveLen(tc.ExpectedRetryCount))
+			if tc.ExpectedPhase == capm3.PhaseSucceeded ||
+				tc.ExpectedPhase == capm3.PhaseFailed {
+				Expect(status.LastRemediated).NotTo(BeNil())
+			}
+
+			savedHost := bmh.BareMetalHost{}
+			err = c.Get(context.TODO(),
+				client.ObjectKey{Name: host.Name, Namespace: host.Namespace},
+				&savedHost,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			_, powerOff := savedHost.Annotations[RemediationPowerOffAnnotation]
+			Expect(powerOff).To(Equal(tc.ExpectHostPowerOff))
+			_, powerOffRequested := savedHost.Annotations[HostPowerOffAnnotation]
+			Expect(powerOffRequested).To(Equal(tc.PowerOffRequested))
+			Expect(savedHost.Spec.Online).To(Equal(
+				!tc.ExpectHostPowerOff && !tc.PowerOffRequested,
+			))
+
+			savedMachine := capi.Machine{}
+			err = c.Get(context.TODO(),
+				client.ObjectKey{Name: machine.Name, Namespace: machine.Namespace},
+				&savedMachine,
+			)
+			if tc.ExpectMachineDeleted {
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+		},
+		Entry("Remediation finished", testCaseRemediate{
+			Status: capm3.BareMetalRemediationStatus{
+				Phase:          capm3.PhaseSucceeded,
+				RetryCount:     1,
+				LastRemediated: &metav1.Time{Time: time.Now()},
+				Attempts: []capm3.RemediationAttempt{
+					remediationAttempt(time.Minute, true, true),
+				},
+			},
+			HostPoweredOn:      true,
+			ExpectedPhase:      capm3.PhaseSucceeded,
+			ExpectedRetryCount: 1,
+		}),
+		Entry("Power cycle started", testCaseRemediate{
+			HostPoweredOn:      true,
+			ExpectRequeue:      true,
+			ExpectedPhase:      capm3.PhaseRunning,
+			ExpectedRetryCount: 1,
+			ExpectHostPowerOff: true,
+		}),
+		Entry("Host powered off, powering on", testCaseRemediate{
+			Status: capm3.BareMetalRemediationStatus{
+				Phase:      capm3.PhaseRunning,
+				RetryCount: 1,
+				Attempts: []capm3.RemediationAttempt{
+					remediationAttempt(time.Minute, false, false),
+				},
+			},
+			HostPowerOff:       true,
+			ExpectRequeue:      true,
+			ExpectedPhase:      capm3.PhaseRunning,
+			ExpectedRetryCount: 1,
+		}),
+		Entry("Host kept powered off by its BareMetalMachine", testCaseRemediate{
+			Status: capm3.BareMetalRemediationStatus{
+				Phase:      capm3.PhaseRunning,
+				RetryCount: 1,
+				Attempts: []capm3.RemediationAttempt{
+					remediationAttempt(time.Minute, false, false),
+				},
+			},
+			HostPowerOff:       true,
+			PowerOffRequested:  true,
+			ExpectRequeue:      true,
+			ExpectedPhase:      capm3.PhaseRunning,
+			ExpectedRetryCount: 1,
+		}),
+		Entry("Host powered on, waiting for the node", testCaseRemediate{
+			Status: capm3.BareMetalRemediationStatus{
+				Phase:      capm3.PhaseRunning,
+				RetryCount: 1,
+				Attempts: []capm3.RemediationAttempt{
+					remediationAttempt(time.Minute, true, false),
+				},
+			},
+			HostPoweredOn:      true,
+			ExpectRequeue:      true,
+			ExpectedPhase:      capm3.PhaseWaiting,
+			ExpectedRetryCount: 1,
+		}),
+		Entry("Node ready", testCaseRemediate{
+			Status: capm3.BareMetalRemediationStatus{
+				Phase:      capm3.PhaseWaiting,
+				RetryCount: 1,
+				Attempts: []capm3.RemediationAttempt{
+					remediationAttempt(time.Minute, true, true),
+				},
+			},
+			HostPoweredOn:      true,
+			NodeReady:          true,
+			ExpectedPhase:      capm3.PhaseSucceeded,
+			ExpectedRetryCount: 1,
+		}),
+		Entry("Power cycle timed out, retrying", testCaseRemediate{
+			Strategy: &capm3.RemediationStrategy{
+				RetryLimit: 2,
+				Timeout:    &metav1.Duration{Duration: time.Minute},
+			},
+			Status: capm3.BareMetalRemediationStatus{
+				Phase:      capm3.PhaseWaiting,
+				RetryCount: 1,
+				Attempts: []capm3.RemediationAttempt{
+					remediationAttempt(time.Hour, true, true),
+				},
+			},
+			HostPoweredOn:      true,
+			ExpectRequeue:      true,
+			ExpectedPhase:      capm3.PhaseRunning,
+			ExpectedRetryCount: 2,
+			ExpectHostPowerOff: true,
+		}),
+		Entry("Retry limit reached, deleting the Machine", testCaseRemediate{
+			Strategy: &capm3.RemediationStrategy{
+				RetryLimit: 1,
+				Timeout:    &metav1.Duration{Duration: time.Minute},
+			},
+			Status: capm3.BareMetalRemediationStatus{
+				Phase:      capm3.PhaseRunning,
+				RetryCount: 1,
+				Attempts: []capm3.RemediationAttempt{
+					remediationAttempt(time.Hour, true, false),
+				},
+			},
+			HostPowerOff:         true,
+			ExpectedPhase:        capm3.PhaseFailed,
+			ExpectedRetryCount:   1,
+			ExpectMachineDeleted: true,
+		}),
+		Entry("No host, deleting the Machine", testCaseRemediate{
+			NoHost:               true,
+			ExpectedPhase:        capm3.PhaseFailed,
+			ExpectMachineDeleted: true,
+		}),
+	)
+
+	It("Sets and unsets the finalizer", func() {
+		remediation := &capm3.BareMetalRemediation{}
+		remediationMgr, err := NewRemediationManager(nil, remediation,
+			&capi.Machine{}, klogr.New(),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		remediationMgr.SetFinalizer()
+		remediationMgr.SetFinalizer()
+		Expect(remediation.Finalizers).To(Equal(
+			[]string{capm3.RemediationFinalizer},
+		))
+
+		remediationMgr.UnsetFinalizer()
+		Expect(remediation.Finalizers).To(BeEmpty())
+	})
+
+	type testCaseRemediationDelete struct {
+		NoHost       bool
+		HostPowerOff bool
+		// PowerOffRequested sets the HostPowerOffAnnotation of a power off
+		// requested on the BareMetalMachine
+		PowerOffRequested bool
+	}
+
+	DescribeTable("Test Delete",
+		func(tc testCaseRemediationDelete) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "myhost",
+					Namespace:   namespaceName,
+					Annotations: map[string]string{},
+				},
+				Spec: bmh.BareMetalHostSpec{
+					Online: !tc.HostPowerOff && !tc.PowerOffRequested,
+				},
+			}
+			if tc.HostPowerOff {
+				host.Annotations[RemediationPowerOffAnnotation] = ""
+			}
+			if tc.PowerOffRequested {
+				host.Annotations[HostPowerOffAnnotation] = ""
+			}
+			bmMachine := &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: namespaceName,
+				},
+			}
+			if !tc.NoHost {
+				bmMachine.Annotations = map[string]string{
+					HostAnnotation: namespaceName + "/myhost",
+				}
+			}
+			machine := &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mymachine",
+					Namespace: namespaceName,
+				},
+				Spec: capi.MachineSpec{
+					InfrastructureRef: corev1.ObjectReference{
+						Name: bmMachine.Name,
+					},
+				},
+			}
+			c := fakeclient.NewFakeClientWithScheme(setupScheme(), host,
+				bmMachine, machine,
+			)
+			remediationMgr, err := NewRemediationManager(c,
+				&capm3.BareMetalRemediation{}, machine, klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = remediationMgr.Delete(context.TODO())
+			Expect(err).NotTo(HaveOccurred())
+
+			savedHost := bmh.BareMetalHost{}
+			err = c.Get(context.TODO(),
+				client.ObjectKey{Name: host.Name, Namespace: host.Namespace},
+				&savedHost,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			_, powerOff := savedHost.Annotations[RemediationPowerOffAnnotation]
+			Expect(powerOff).To(Equal(tc.NoHost && tc.HostPowerOff))
+			if tc.NoHost {
+				Expect(savedHost.Spec.Online).To(Equal(host.Spec.Online))
+			} else {
+				Expect(savedHost.Spec.Online).To(Equal(!tc.PowerOffRequested))
+			}
+		},
+		Entry("Host powered off by the remediation", testCaseRemediationDelete{
+			HostPowerOff: true,
+		}),
+		Entry("Host kept powered off by its BareMetalMachine", testCaseRemediationDelete{
+			HostPowerOff:      true,
+			PowerOffRequested: true,
+		}),
+		Entry("Host powered on", testCaseRemediationDelete{}),
+		Entry("No host", testCaseRemediationDelete{
+			NoHost:       true,
+			HostPowerOff: true,
+		}),
+	)
+})
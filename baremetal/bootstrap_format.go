@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const (
+	// BootstrapFormatLabel is the label set on the user data secret created
+	// for the BareMetalHost, giving the format of the bootstrap data
+	BootstrapFormatLabel = "metal3.io/bootstrap-format"
+
+	defaultBootstrapSecretKey = "value"
+
+	// templateHeader starts the first line of user data that cloud-init
+	// renders as a template before processing it, like the bootstrap data
+	// generated by the kubeadm bootstrap provider
+	templateHeader = "## template:"
+)
+
+// BootstrapFormat is the format of the bootstrap data of a Machine
+type BootstrapFormat string
+
+const (
+	// BootstrapFormatCloudConfig is a cloud-init cloud-config
+	BootstrapFormatCloudConfig BootstrapFormat = "cloud-config"
+	// BootstrapFormatIgnition is an Ignition config
+	BootstrapFormatIgnition BootstrapFormat = "ignition"
+	// BootstrapFormatShellScript is a script run by cloud-init
+	BootstrapFormatShellScript BootstrapFormat = "shell-script"
+)
+
+// bootstrapSecretKey returns the key of the bootstrap data in the secret
+// referenced by the Machine
+func (m *MachineManager) bootstrapSecretKey() string {
+	if m.BareMetalMachine.Spec.BootstrapSecretKey != "" {
+		return m.BareMetalMachine.Spec.BootstrapSecretKey
+	}
+	return defaultBootstrapSecretKey
+}
+
+// isKnownBootstrapFormat returns whether the format is one of the formats
+// of bootstrap data known to the machine manager.
+func isKnownBootstrapFormat(format BootstrapFormat) bool {
+	switch format {
+	case BootstrapFormatCloudConfig, BootstrapFormatIgnition,
+		BootstrapFormatShellScript:
+		return true
+	}
+	return false
+}
+
+// detectBootstrapFormat returns the format of the bootstrap data, or an empty
+// format if it is not recognized. A template header line, as in
+// "## template: jinja", is skipped.
+func detectBootstrapFormat(data []byte) BootstrapFormat {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte(templateHeader)) {
+		lineEnd := bytes.IndexByte(data, '\n')
+		if lineEnd < 0 {
+			return ""
+		}
+		data = bytes.TrimSpace(data[lineEnd+1:])
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte("#cloud-config")):
+		return BootstrapFormatCloudConfig
+	case bytes.HasPrefix(data, []byte("#!")):
+		return BootstrapFormatShellScript
+	case bytes.HasPrefix(data, []byte("{")):
+		config := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return ""
+		}
+		if _, ok := config["ignition"]; ok {
+			return BootstrapFormatIgnition
+		}
+	}
+	return ""
+}
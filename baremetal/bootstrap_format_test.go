@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// cabpkBootstrapData is the start of the bootstrap data generated by the
+// kubeadm bootstrap provider, a cloud-config rendered by cloud-init as a
+// Jinja template
+const cabpkBootstrapData = `## template: jinja
+#cloud-config
+
+write_files:
+-   path: /etc/kubernetes/pki/ca.crt
+    owner: root:root
+    permissions: '0640'
+    content: |
+      -----BEGIN CERTIFICATE-----
+      MIICyzCCAbOgAwIBAgIBADANBgkqhkiG9w0BAQsFADAVMRMwEQYDVQQDEwprdWJl
+      -----END CERTIFICATE-----
+
+-   path: /tmp/kubeadm.yaml
+    owner: root:root
+    permissions: '0640'
+    content: |
+      ---
+      apiVersion: kubeadm.k8s.io/v1beta1
+      kind: InitConfiguration
+      nodeRegistration:
+        name: '{{ ds.meta_data.local_hostname }}'
+        kubeletExtraArgs:
+          node-labels: metal3.io/uuid={{ ds.meta_data.uuid }}
+
+runcmd:
+  - 'kubeadm init --config /tmp/kubeadm.yaml '
+`
+
+var _ = DescribeTable("Test detectBootstrapFormat",
+	func(data string, expected BootstrapFormat) {
+		Expect(detectBootstrapFormat([]byte(data))).To(Equal(expected))
+	},
+	Entry("cloud-config", "#cloud-config\nruncmd: []\n", BootstrapFormatCloudConfig),
+	Entry("cloud-config after blank lines", "\n\n#cloud-config\n",
+		BootstrapFormatCloudConfig,
+	),
+	Entry("cloud-config generated by the kubeadm bootstrap provider",
+		cabpkBootstrapData, BootstrapFormatCloudConfig,
+	),
+	Entry("shell script template", "## template: jinja\n#!/bin/bash\necho foo\n",
+		BootstrapFormatShellScript,
+	),
+	Entry("template header only", "## template: jinja", BootstrapFormat("")),
+	Entry("shell script", "#!/bin/bash\necho foo\n", BootstrapFormatShellScript),
+	Entry("ignition", `{"ignition": {"version": "2.2.0"}}`, BootstrapFormatIgnition),
+	Entry("JSON, not ignition", `{"foo": "bar"}`, BootstrapFormat("")),
+	Entry("invalid JSON", `{"ignition": `, BootstrapFormat("")),
+	Entry("unknown", "FooBar\n", BootstrapFormat("")),
+)
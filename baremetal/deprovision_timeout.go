@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+)
+
+// ForceReleaseAnnotation is the annotation an operator sets on a
+// BareMetalMachine being deleted to release its BareMetalHost without
+// waiting for the host to be deprovisioned, e.g. when its BMC is dead, so
+// that the BareMetalMachine can be finalized.
+const ForceReleaseAnnotation = "metal3.io/force-release"
+
+// forceReleaseRequested returns whether an operator requested the release of
+// the host without waiting for its deprovisioning.
+func (m *MachineManager) forceReleaseRequested() bool {
+	_, ok := m.BareMetalMachine.Annotations[ForceReleaseAnnotation]
+	return ok
+}
+
+// startDeprovisioning records the start of the deprovisioning of the host,
+// if not already done.
+func (m *MachineManager) startDeprovisioning() {
+	if m.BareMetalMachine.Status.Deprovisioning == nil {
+		m.BareMetalMachine.Status.Deprovisioning = &capm3.DeprovisioningStatus{
+			StartedAt: metav1.Now(),
+		}
+	}
+}
+
+// checkDeprovisionTimeout sets the BareMetalMachine in error if the host is
+// still not deprovisioned once the deprovision timeout expired. An event is
+// created on the host the first time.
+func (m *MachineManager) checkDeprovisionTimeout(ctx context.Context,
+	host *bmh.BareMetalHost,
+) error {
+	m.startDeprovisioning()
+	status := m.BareMetalMachine.Status.Deprovisioning
+	if m.DeprovisionTimeout <= 0 ||
+		time.Since(status.StartedAt.Time) <= m.DeprovisionTimeout {
+		return nil
+	}
+
+	message := fmt.Sprintf("Deprovisioning of BareMetalHost %s timed out after %v, set the %s annotation to release it",
+		host.Name, m.DeprovisionTimeout, ForceReleaseAnnotation,
+	)
+	m.setError(message, capierrors.DeleteMachineError)
+	if status.TimedOut {
+		return nil
+	}
+	m.Log.Info("Deprovisioning timed out", "host", host.Name,
+		"timeout", m.DeprovisionTimeout,
+	)
+	status.TimedOut = true
+	return m.createHostEvent(ctx, host, corev1.EventTypeWarning,
+		"DeprovisioningTimedOut", message,
+	)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/klogr"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Deprovision timeout", func() {
+
+	type testCaseCheckDeprovisionTimeout struct {
+		Timeout        time.Duration
+		Status         *capm3.DeprovisioningStatus
+		ExpectedError  bool
+		ExpectedEvents int
+	}
+
+	DescribeTable("Test checkDeprovisionTimeout",
+		func(tc testCaseCheckDeprovisionTimeout) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "myhost",
+					Namespace: "myns",
+				},
+			}
+			bmMachine := &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: "myns",
+				},
+				Status: capm3.BareMetalMachineStatus{
+					Deprovisioning: tc.Status,
+				},
+			}
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm())
+			machineMgr, err := NewMachineManager(c, nil, nil, nil, bmMachine,
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			machineMgr.DeprovisionTimeout = tc.Timeout
+
+			err = machineMgr.checkDeprovisionTimeout(context.TODO(), host)
+			Expect(err).NotTo(HaveOccurred())
+
+			status := bmMachine.Status.Deprovisioning
+			Expect(status).NotTo(BeNil())
+			Expect(status.TimedOut).To(Equal(tc.ExpectedError))
+			if tc.ExpectedError {
+				Expect(bmMachine.Status.FailureReason).NotTo(BeNil())
+				Expect(*bmMachine.Status.FailureReason).To(
+					Equal(capierrors.DeleteMachineError),
+				)
+				Expect(*bmMachine.Status.FailureMessage).To(
+					ContainSubstring(ForceReleaseAnnotation),
+				)
+			} else {
+				Expect(bmMachine.Status.FailureReason).To(BeNil())
+			}
+
+			events := corev1.EventList{}
+			Expect(c.List(context.TODO(), &events)).To(Succeed())
+			Expect(events.Items).To(HaveLen(tc.ExpectedEvents))
+			if tc.ExpectedEvents > 0 {
+				Expect(events.Items[0].Reason).To(Equal("DeprovisioningTimedOut"))
+			}
+		},
+		Entry("Deprovisioning started", testCaseCheckDeprovisionTimeout{
+			Timeout: time.Hour,
+		}),
+		Entry("Timeout disabled", testCaseCheckDeprovisionTimeout{
+			Status: &capm3.DeprovisioningStatus{
+				StartedAt: metav1.Time{Time: time.Now().Add(-24 * time.Hour)},
+			},
+		}),
+		Entry("Timeout not expired", testCaseCheckDeprovisionTimeout{
+			Timeout: time.Hour,
+			Status: &capm3.DeprovisioningStatus{
+				StartedAt: metav1.Time{Time: time.Now().Add(-time.Minute)},
+			},
+		}),
+		Entry("Timeout expired", testCaseCheckDeprovisionTimeout{
+			Timeout: time.Hour,
+			Status: &capm3.DeprovisioningStatus{
+				StartedAt: metav1.Time{Time: time.Now().Add(-2 * time.Hour)},
+			},
+			ExpectedError:  true,
+			ExpectedEvents: 1,
+		}),
+		Entry("Timeout already expired", testCaseCheckDeprovisionTimeout{
+			Timeout: time.Hour,
+			Status: &capm3.DeprovisioningStatus{
+				StartedAt: metav1.Time{Time: time.Now().Add(-2 * time.Hour)},
+				TimedOut:  true,
+			},
+			ExpectedError: true,
+		}),
+	)
+})
@@ -0,0 +1,146 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// extraUserDataMergeType is the cloud-init merge type of the extra user data
+// parts, so that e.g. their SSH keys are appended to the ones of the
+// bootstrap data instead of replacing them
+const extraUserDataMergeType = "list(append)+dict(no_replace,recurse_list)+str()"
+
+// userDataContentType returns the MIME content type of user data of the given
+// format. User data starting with a template header is rendered by cloud-init
+// before being processed according to its format.
+func userDataContentType(data []byte, format BootstrapFormat) string {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(templateHeader)) {
+		return "text/jinja2"
+	}
+	switch format {
+	case BootstrapFormatCloudConfig:
+		return "text/cloud-config"
+	case BootstrapFormatShellScript:
+		return "text/x-shellscript"
+	}
+	return "text/plain"
+}
+
+// extraUserData returns the extra user data of the BareMetalMachine, read
+// from the referenced Secrets. A missing optional Secret or key is skipped.
+func (m *MachineManager) extraUserData(ctx context.Context) ([][]byte, error) {
+	var extraUserData [][]byte
+	for _, ref := range m.BareMetalMachine.Spec.ExtraUserData {
+		optional := ref.Optional != nil && *ref.Optional
+		secret := corev1.Secret{}
+		key := client.ObjectKey{
+			Name:      ref.Name,
+			Namespace: m.BareMetalMachine.Namespace,
+		}
+		err := m.client.Get(ctx, key, &secret)
+		if apierrors.IsNotFound(err) && optional {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the extra user data secret %s",
+				ref.Name,
+			)
+		}
+		data, ok := secret.Data[ref.Key]
+		if !ok {
+			if optional {
+				continue
+			}
+			return nil, errors.Errorf("key %s not found in the extra user data secret %s",
+				ref.Key, ref.Name,
+			)
+		}
+		extraUserData = append(extraUserData, data)
+	}
+	return extraUserData, nil
+}
+
+// mergeExtraUserData merges the extra user data of the BareMetalMachine with
+// the bootstrap data into a MIME multipart document, processed by cloud-init.
+// The bootstrap data is returned unchanged when there is no extra user data.
+// Extra user data can not be merged into Ignition bootstrap data.
+func (m *MachineManager) mergeExtraUserData(ctx context.Context,
+	bootstrapData []byte, format BootstrapFormat,
+) ([]byte, error) {
+	extraUserData, err := m.extraUserData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(extraUserData) == 0 {
+		return bootstrapData, nil
+	}
+	if format == BootstrapFormatIgnition {
+		return nil, errors.New("extra user data can not be merged into Ignition bootstrap data")
+	}
+
+	// The boundary is derived from the content, so that the document only
+	// changes with its parts
+	hash := sha256.New()
+	hash.Write(bootstrapData)
+	for _, data := range extraUserData {
+		hash.Write(data)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.SetBoundary(fmt.Sprintf("%x", hash.Sum(nil))); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&body, "Content-Type: multipart/mixed; boundary=\"%s\"\r\n",
+		writer.Boundary(),
+	)
+	fmt.Fprintf(&body, "MIME-Version: 1.0\r\n\r\n")
+
+	parts := append([][]byte{bootstrapData}, extraUserData...)
+	for i, data := range parts {
+		partFormat := format
+		header := textproto.MIMEHeader{}
+		if i > 0 {
+			partFormat = detectBootstrapFormat(data)
+			header.Set("Merge-Type", extraUserDataMergeType)
+		}
+		header.Set("Content-Type",
+			userDataContentType(data, partFormat)+"; charset=\"utf-8\"",
+		)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
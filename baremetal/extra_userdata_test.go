@@ -0,0 +1,128 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/klogr"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Extra user data", func() {
+
+	type testCaseMergeExtraUserData struct {
+		BootstrapData        string
+		ExtraUserData        string
+		ExpectedContentTypes []string
+	}
+
+	DescribeTable("Test mergeExtraUserData",
+		func(tc testCaseMergeExtraUserData) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ssh-keys",
+					Namespace: "myns",
+				},
+				Data: map[string][]byte{
+					"cloud-config": []byte(tc.ExtraUserData),
+				},
+			}
+			bmMachine := &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: "myns",
+				},
+				Spec: capm3.BareMetalMachineSpec{
+					ExtraUserData: []corev1.SecretKeySelector{
+						corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "ssh-keys",
+							},
+							Key: "cloud-config",
+						},
+					},
+				},
+			}
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm(), secret)
+			machineMgr, err := NewMachineManager(c, nil, nil, nil, bmMachine,
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			bootstrapData := []byte(tc.BootstrapData)
+			userData, err := machineMgr.mergeExtraUserData(context.TODO(),
+				bootstrapData, detectBootstrapFormat(bootstrapData),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Read the parts as cloud-init does
+			msg, err := mail.ReadMessage(bytes.NewReader(userData))
+			Expect(err).NotTo(HaveOccurred())
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mediaType).To(Equal("multipart/mixed"))
+
+			reader := multipart.NewReader(msg.Body, params["boundary"])
+			contentTypes := []string{}
+			parts := []string{}
+			for {
+				part, err := reader.NextPart()
+				if err == io.EOF {
+					break
+				}
+				Expect(err).NotTo(HaveOccurred())
+				contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+				Expect(err).NotTo(HaveOccurred())
+				contentTypes = append(contentTypes, contentType)
+				data, err := ioutil.ReadAll(part)
+				Expect(err).NotTo(HaveOccurred())
+				parts = append(parts, string(data))
+			}
+			Expect(contentTypes).To(Equal(tc.ExpectedContentTypes))
+			Expect(parts).To(Equal([]string{tc.BootstrapData, tc.ExtraUserData}))
+		},
+		Entry("kubeadm bootstrap data", testCaseMergeExtraUserData{
+			BootstrapData:        cabpkBootstrapData,
+			ExtraUserData:        "#cloud-config\nssh_authorized_keys: []\n",
+			ExpectedContentTypes: []string{"text/jinja2", "text/cloud-config"},
+		}),
+		Entry("cloud-config bootstrap data", testCaseMergeExtraUserData{
+			BootstrapData:        "#cloud-config\nruncmd: []\n",
+			ExtraUserData:        "#!/bin/bash\necho foo\n",
+			ExpectedContentTypes: []string{"text/cloud-config", "text/x-shellscript"},
+		}),
+		Entry("extra user data template", testCaseMergeExtraUserData{
+			BootstrapData:        "#!/bin/bash\necho foo\n",
+			ExtraUserData:        "## template: jinja\n#cloud-config\nhostname: {{ v1.local_hostname }}\n",
+			ExpectedContentTypes: []string{"text/x-shellscript", "text/jinja2"},
+		}),
+	)
+})
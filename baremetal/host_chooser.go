@@ -0,0 +1,191 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/pkg/errors"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+const (
+	// HostRackLabel is the label on a BareMetalHost giving the rack the host
+	// is in. It is used by the rack aware host selection strategies when the
+	// BareMetalCluster has no FailureDomainLabel.
+	HostRackLabel = "metal3.io/rack"
+)
+
+var (
+	// hostRand picks among the hosts with the highest score. It is seeded
+	// once, so that machines chosen at the same time pick different hosts.
+	hostRand     = rand.New(rand.NewSource(time.Now().UnixNano()))
+	hostRandLock sync.Mutex
+)
+
+// HostChooser scores the BareMetalHosts available for a BareMetalMachine. The
+// host with the highest score is the one chosen.
+type HostChooser interface {
+	// Strategy returns the host selection strategy implemented.
+	Strategy() capm3.HostSelectionStrategy
+	// Score returns the score of the host. Higher is better.
+	Score(host *bmh.BareMetalHost) int64
+}
+
+// NewHostChooser returns the HostChooser implementing the given strategy.
+// hosts are all the hosts the machine can see, whether they are consumed or
+// not, clusterName is the name of the cluster the machine belongs to and
+// rackLabel the label giving the rack of a host, HostRackLabel if empty.
+func NewHostChooser(strategy capm3.HostSelectionStrategy,
+	hosts []bmh.BareMetalHost, clusterName string, rackLabel string,
+) (HostChooser, error) {
+	switch strategy {
+	case "", capm3.HostSelectionRandom:
+		return randomChooser{}, nil
+	case capm3.HostSelectionLeastRecentlyUsed:
+		return lruChooser{now: time.Now()}, nil
+	case capm3.HostSelectionBestFit:
+		return bestFitChooser{}, nil
+	case capm3.HostSelectionPackByRack, capm3.HostSelectionSpreadByRack:
+		return newRackChooser(strategy, hosts, clusterName, rackLabel), nil
+	}
+	return nil, errors.Errorf("unknown host selection strategy %q", strategy)
+}
+
+// chooseFrom returns the host with the highest score and its score. If
+// several hosts have the highest score, one of them is picked at random.
+func chooseFrom(chooser HostChooser, hosts []*bmh.BareMetalHost) (*bmh.BareMetalHost, int64) {
+	var bestScore int64
+	bestHosts := []*bmh.BareMetalHost{}
+	for _, host := range hosts {
+		score := chooser.Score(host)
+		switch {
+		case len(bestHosts) == 0 || score > bestScore:
+			bestHosts = []*bmh.BareMetalHost{host}
+			bestScore = score
+		case score == bestScore:
+			bestHosts = append(bestHosts, host)
+		}
+	}
+	if len(bestHosts) == 0 {
+		return nil, 0
+	}
+
+	hostRandLock.Lock()
+	defer hostRandLock.Unlock()
+	return bestHosts[hostRand.Intn(len(bestHosts))], bestScore
+}
+
+// randomChooser gives the same score to all hosts, so that the host is
+// picked at random.
+type randomChooser struct{}
+
+func (randomChooser) Strategy() capm3.HostSelectionStrategy {
+	return capm3.HostSelectionRandom
+}
+
+func (randomChooser) Score(host *bmh.BareMetalHost) int64 {
+	return 0
+}
+
+// lruChooser scores hosts with the number of seconds since they were last
+// deprovisioned, or created if they never were.
+type lruChooser struct {
+	now time.Time
+}
+
+func (lruChooser) Strategy() capm3.HostSelectionStrategy {
+	return capm3.HostSelectionLeastRecentlyUsed
+}
+
+func (c lruChooser) Score(host *bmh.BareMetalHost) int64 {
+	lastUsed := host.CreationTimestamp.Time
+	deprovisioned := host.Status.OperationHistory.Deprovision.End.Time
+	if deprovisioned.After(lastUsed) {
+		lastUsed = deprovisioned
+	}
+	return int64(c.now.Sub(lastUsed).Seconds())
+}
+
+// bestFitChooser scores hosts with the opposite of the size of their
+// hardware, that is the sum of the number of CPUs, the RAM in GiB and the
+// disk size in GiB. Hosts without hardware details get the lowest score.
+type bestFitChooser struct{}
+
+func (bestFitChooser) Strategy() capm3.HostSelectionStrategy {
+	return capm3.HostSelectionBestFit
+}
+
+func (bestFitChooser) Score(host *bmh.BareMetalHost) int64 {
+	hardware := host.Status.HardwareDetails
+	if hardware == nil {
+		return math.MinInt64
+	}
+	size := int64(hardware.CPU.Count) + int64(hardware.RAMMebibytes/1024)
+	for _, disk := range hardware.Storage {
+		size += int64(disk.SizeBytes / bmh.GibiByte)
+	}
+	return -size
+}
+
+// rackChooser scores hosts with the number of hosts of the cluster in their
+// rack, positively to pack the cluster in as few racks as possible or
+// negatively to spread it over as many racks as possible. Hosts without
+// rack label are all considered to be in the same rack.
+type rackChooser struct {
+	strategy     capm3.HostSelectionStrategy
+	rackLabel    string
+	clusterHosts map[string]int64
+}
+
+func newRackChooser(strategy capm3.HostSelectionStrategy,
+	hosts []bmh.BareMetalHost, clusterName string, rackLabel string,
+) rackChooser {
+	if rackLabel == "" {
+		rackLabel = HostRackLabel
+	}
+	clusterHosts := make(map[string]int64)
+	for _, host := range hosts {
+		if host.Spec.ConsumerRef == nil ||
+			host.Labels[capi.ClusterLabelName] != clusterName {
+			continue
+		}
+		clusterHosts[host.Labels[rackLabel]]++
+	}
+	return rackChooser{
+		strategy:     strategy,
+		rackLabel:    rackLabel,
+		clusterHosts: clusterHosts,
+	}
+}
+
+func (c rackChooser) Strategy() capm3.HostSelectionStrategy {
+	return c.strategy
+}
+
+func (c rackChooser) Score(host *bmh.BareMetalHost) int64 {
+	score := c.clusterHosts[host.Labels[c.rackLabel]]
+	if c.strategy == capm3.HostSelectionSpreadByRack {
+		return -score
+	}
+	return score
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func chooserHost(name string, labels map[string]string,
+	consumed bool, hardware *bmh.HardwareDetails, created time.Time,
+	deprovisioned time.Time,
+) bmh.BareMetalHost {
+	host := bmh.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "myns",
+			Labels:            labels,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Status: bmh.BareMetalHostStatus{
+			HardwareDetails: hardware,
+			OperationHistory: bmh.OperationHistory{
+				Deprovision: bmh.OperationMetric{
+					End: metav1.NewTime(deprovisioned),
+				},
+			},
+		},
+	}
+	if consumed {
+		host.Spec.ConsumerRef = &corev1.ObjectReference{
+			Name:      "somemachine",
+			Namespace: "myns",
+		}
+	}
+	return host
+}
+
+func hardwareOfSize(cpus int, ramGiB int, diskGiB int) *bmh.HardwareDetails {
+	return &bmh.HardwareDetails{
+		CPU:          bmh.CPU{Count: cpus},
+		RAMMebibytes: ramGiB * 1024,
+		Storage: []bmh.Storage{
+			{SizeBytes: bmh.Capacity(diskGiB) * bmh.GibiByte},
+		},
+	}
+}
+
+var _ = Describe("Host chooser", func() {
+	now := time.Now()
+	rackLabels := func(rack string, cluster string) map[string]string {
+		return map[string]string{
+			HostRackLabel:         rack,
+			capi.ClusterLabelName: cluster,
+		}
+	}
+
+	smallHost := chooserHost("small", rackLabels("rack1", ""), false,
+		hardwareOfSize(4, 16, 100), now.Add(-time.Hour), time.Time{},
+	)
+	bigHost := chooserHost("big", rackLabels("rack2", ""), false,
+		hardwareOfSize(64, 512, 2000), now.Add(-time.Hour), time.Time{},
+	)
+	oldHost := chooserHost("old", rackLabels("rack2", ""), false,
+		nil, now.Add(-48*time.Hour), now.Add(-24*time.Hour),
+	)
+	newHost := chooserHost("new", rackLabels("rack1", ""), false,
+		nil, now.Add(-48*time.Hour), now.Add(-time.Minute),
+	)
+	clusterHostRack1 := chooserHost("cluster1", rackLabels("rack1", clusterName),
+		true, nil, now, time.Time{},
+	)
+	otherClusterHostRack2 := chooserHost("other1",
+		rackLabels("rack2", "othercluster"), true, nil, now, time.Time{},
+	)
+	// The same hosts, with their rack in a failure domain label
+	failureDomainLabels := func(rack string, cluster string) map[string]string {
+		return map[string]string{
+			"example.com/rack":    rack,
+			capi.ClusterLabelName: cluster,
+		}
+	}
+	smallHostDomain1 := chooserHost("small", failureDomainLabels("rack1", ""),
+		false, hardwareOfSize(4, 16, 100), now.Add(-time.Hour), time.Time{},
+	)
+	bigHostDomain2 := chooserHost("big", failureDomainLabels("rack2", ""),
+		false, hardwareOfSize(64, 512, 2000), now.Add(-time.Hour), time.Time{},
+	)
+	clusterHostDomain2 := chooserHost("cluster1",
+		failureDomainLabels("rack2", clusterName), true, nil, now, time.Time{},
+	)
+
+	type testCaseChooser struct {
+		Strategy         capm3.HostSelectionStrategy
+		RackLabel        string
+		AllHosts         []bmh.BareMetalHost
+		AvailableHosts   []bmh.BareMetalHost
+		ExpectedStrategy capm3.HostSelectionStrategy
+		ExpectedHostName string
+		ExpectError      bool
+	}
+
+	DescribeTable("Test chooseFrom",
+		func(tc testCaseChooser) {
+			chooser, err := NewHostChooser(tc.Strategy, tc.AllHosts, clusterName,
+				tc.RackLabel,
+			)
+			if tc.ExpectError {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chooser.Strategy()).To(Equal(tc.ExpectedStrategy))
+
+			availableHosts := []*bmh.BareMetalHost{}
+			for i := range tc.AvailableHosts {
+				availableHosts = append(availableHosts, &tc.AvailableHosts[i])
+			}
+			host, score := chooseFrom(chooser, availableHosts)
+			if tc.ExpectedHostName == "" {
+				Expect(host).To(BeNil())
+				return
+			}
+			Expect(host).NotTo(BeNil())
+			Expect(host.Name).To(Equal(tc.ExpectedHostName))
+			Expect(score).To(Equal(chooser.Score(host)))
+		},
+		Entry("Random by default", testCaseChooser{
+			Strategy:         "",
+			AvailableHosts:   []bmh.BareMetalHost{smallHost},
+			ExpectedStrategy: capm3.HostSelectionRandom,
+			ExpectedHostName: "small",
+		}),
+		Entry("No host available", testCaseChooser{
+			Strategy:         capm3.HostSelectionBestFit,
+			AvailableHosts:   []bmh.BareMetalHost{},
+			ExpectedStrategy: capm3.HostSelectionBestFit,
+		}),
+		Entry("Least recently used", testCaseChooser{
+			Strategy:         capm3.HostSelectionLeastRecentlyUsed,
+			AvailableHosts:   []bmh.BareMetalHost{newHost, oldHost, smallHost},
+			ExpectedStrategy: capm3.HostSelectionLeastRecentlyUsed,
+			ExpectedHostName: "old",
+		}),
+		Entry("Best fit", testCaseChooser{
+			Strategy:         capm3.HostSelectionBestFit,
+			AvailableHosts:   []bmh.BareMetalHost{bigHost, oldHost, smallHost},
+			ExpectedStrategy: capm3.HostSelectionBestFit,
+			ExpectedHostName: "small",
+		}),
+		Entry("Pack by rack", testCaseChooser{
+			Strategy: capm3.HostSelectionPackByRack,
+			AllHosts: []bmh.BareMetalHost{clusterHostRack1,
+				otherClusterHostRack2, smallHost, bigHost,
+			},
+			AvailableHosts:   []bmh.BareMetalHost{bigHost, smallHost},
+			ExpectedStrategy: capm3.HostSelectionPackByRack,
+			ExpectedHostName: "small",
+		}),
+		Entry("Spread by rack", testCaseChooser{
+			Strategy: capm3.HostSelectionSpreadByRack,
+			AllHosts: []bmh.BareMetalHost{clusterHostRack1,
+				otherClusterHostRack2, smallHost, bigHost,
+			},
+			AvailableHosts:   []bmh.BareMetalHost{smallHost, bigHost},
+			ExpectedStrategy: capm3.HostSelectionSpreadByRack,
+			ExpectedHostName: "big",
+		}),
+		Entry("Pack by rack with the failure domain label", testCaseChooser{
+			Strategy:  capm3.HostSelectionPackByRack,
+			RackLabel: "example.com/rack",
+			AllHosts: []bmh.BareMetalHost{clusterHostDomain2,
+				smallHostDomain1, bigHostDomain2,
+			},
+			AvailableHosts:   []bmh.BareMetalHost{smallHostDomain1, bigHostDomain2},
+			ExpectedStrategy: capm3.HostSelectionPackByRack,
+			ExpectedHostName: "big",
+		}),
+		Entry("Spread by rack with the failure domain label", testCaseChooser{
+			Strategy:  capm3.HostSelectionSpreadByRack,
+			RackLabel: "example.com/rack",
+			AllHosts: []bmh.BareMetalHost{clusterHostDomain2,
+				smallHostDomain1, bigHostDomain2,
+			},
+			AvailableHosts:   []bmh.BareMetalHost{smallHostDomain1, bigHostDomain2},
+			ExpectedStrategy: capm3.HostSelectionSpreadByRack,
+			ExpectedHostName: "small",
+		}),
+		Entry("Unknown strategy", testCaseChooser{
+			Strategy:    "pancakes",
+			ExpectError: true,
+		}),
+	)
+})
@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/pkg/errors"
+)
+
+// checkHardwareRequirements returns an error giving the reason why the host
+// does not meet the hardware requirements, or nil if it meets them or if
+// there are no requirements.
+func checkHardwareRequirements(requirements *capm3.HardwareRequirements,
+	host *bmh.BareMetalHost,
+) error {
+	if requirements == nil {
+		return nil
+	}
+	hardware := host.Status.HardwareDetails
+	if hardware == nil {
+		return errors.New("no hardware details, the host was not inspected")
+	}
+
+	if hardware.CPU.Count < requirements.MinCPUCount {
+		return errors.Errorf("%d CPUs found, %d required",
+			hardware.CPU.Count, requirements.MinCPUCount,
+		)
+	}
+	if hardware.RAMMebibytes < requirements.MinRAMMebibytes {
+		return errors.Errorf("%d MiB of RAM found, %d required",
+			hardware.RAMMebibytes, requirements.MinRAMMebibytes,
+		)
+	}
+
+	var biggestDisk bmh.Capacity
+	for _, disk := range hardware.Storage {
+		if disk.SizeBytes > biggestDisk {
+			biggestDisk = disk.SizeBytes
+		}
+	}
+	if biggestDisk < bmh.Capacity(requirements.MinDiskSizeGibibytes)*bmh.GibiByte {
+		return errors.Errorf("biggest disk of %d GiB found, %d required",
+			biggestDisk/bmh.GibiByte, requirements.MinDiskSizeGibibytes,
+		)
+	}
+
+	if len(hardware.NIC) < requirements.MinNICCount {
+		return errors.Errorf("%d NICs found, %d required",
+			len(hardware.NIC), requirements.MinNICCount,
+		)
+	}
+	if requirements.CPUArchitecture != "" &&
+		hardware.CPU.Arch != requirements.CPUArchitecture {
+		return errors.Errorf("CPU architecture %q found, %q required",
+			hardware.CPU.Arch, requirements.CPUArchitecture,
+		)
+	}
+	return nil
+}
+
+// checkFailureDomain returns an error giving the reason why the host is not
+// in the failure domain of the machine, or nil if it is or if either the
+// failure domain or the label giving the failure domain of the hosts is
+// not set.
+func checkFailureDomain(failureDomainLabel string, failureDomain *string,
+	host *bmh.BareMetalHost,
+) error {
+	if failureDomainLabel == "" || failureDomain == nil || *failureDomain == "" {
+		return nil
+	}
+	hostFailureDomain, ok := host.Labels[failureDomainLabel]
+	if !ok {
+		return errors.Errorf("label %q not set", failureDomainLabel)
+	}
+	if hostFailureDomain != *failureDomain {
+		return errors.Errorf("failure domain %q found, %q required",
+			hostFailureDomain, *failureDomain,
+		)
+	}
+	return nil
+}
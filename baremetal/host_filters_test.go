@@ -0,0 +1,161 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func hostHardware() *bmh.HardwareDetails {
+	return &bmh.HardwareDetails{
+		CPU: bmh.CPU{
+			Arch:  "x86_64",
+			Count: 8,
+		},
+		RAMMebibytes: 16384,
+		Storage: []bmh.Storage{
+			{SizeBytes: 100 * bmh.GibiByte},
+			{SizeBytes: 500 * bmh.GibiByte},
+		},
+		NIC: []bmh.NIC{
+			{Name: "eth0"},
+			{Name: "eth1"},
+		},
+	}
+}
+
+var _ = Describe("Host filters", func() {
+
+	type testCaseHardwareRequirements struct {
+		Requirements  *capm3.HardwareRequirements
+		Hardware      *bmh.HardwareDetails
+		ExpectedError string
+	}
+
+	DescribeTable("Test checkHardwareRequirements",
+		func(tc testCaseHardwareRequirements) {
+			host := &bmh.BareMetalHost{
+				Status: bmh.BareMetalHostStatus{
+					HardwareDetails: tc.Hardware,
+				},
+			}
+			err := checkHardwareRequirements(tc.Requirements, host)
+			if tc.ExpectedError == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(tc.ExpectedError))
+			}
+		},
+		Entry("No requirements", testCaseHardwareRequirements{
+			Requirements: nil,
+			Hardware:     nil,
+		}),
+		Entry("Host not inspected", testCaseHardwareRequirements{
+			Requirements:  &capm3.HardwareRequirements{MinCPUCount: 1},
+			Hardware:      nil,
+			ExpectedError: "no hardware details, the host was not inspected",
+		}),
+		Entry("All requirements met", testCaseHardwareRequirements{
+			Requirements: &capm3.HardwareRequirements{
+				MinCPUCount:          8,
+				MinRAMMebibytes:      16384,
+				MinDiskSizeGibibytes: 500,
+				MinNICCount:          2,
+				CPUArchitecture:      "x86_64",
+			},
+			Hardware: hostHardware(),
+		}),
+		Entry("Not enough CPUs", testCaseHardwareRequirements{
+			Requirements:  &capm3.HardwareRequirements{MinCPUCount: 16},
+			Hardware:      hostHardware(),
+			ExpectedError: "8 CPUs found, 16 required",
+		}),
+		Entry("Not enough RAM", testCaseHardwareRequirements{
+			Requirements:  &capm3.HardwareRequirements{MinRAMMebibytes: 32768},
+			Hardware:      hostHardware(),
+			ExpectedError: "16384 MiB of RAM found, 32768 required",
+		}),
+		Entry("Disk too small", testCaseHardwareRequirements{
+			Requirements:  &capm3.HardwareRequirements{MinDiskSizeGibibytes: 1000},
+			Hardware:      hostHardware(),
+			ExpectedError: "biggest disk of 500 GiB found, 1000 required",
+		}),
+		Entry("Not enough NICs", testCaseHardwareRequirements{
+			Requirements:  &capm3.HardwareRequirements{MinNICCount: 4},
+			Hardware:      hostHardware(),
+			ExpectedError: "2 NICs found, 4 required",
+		}),
+		Entry("Wrong CPU architecture", testCaseHardwareRequirements{
+			Requirements:  &capm3.HardwareRequirements{CPUArchitecture: "aarch64"},
+			Hardware:      hostHardware(),
+			ExpectedError: "CPU architecture \"x86_64\" found, \"aarch64\" required",
+		}),
+	)
+
+	type testCaseFailureDomain struct {
+		FailureDomainLabel string
+		FailureDomain      *string
+		HostLabels         map[string]string
+		ExpectedError      string
+	}
+
+	DescribeTable("Test checkFailureDomain",
+		func(tc testCaseFailureDomain) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: tc.HostLabels,
+				},
+			}
+			err := checkFailureDomain(tc.FailureDomainLabel, tc.FailureDomain, host)
+			if tc.ExpectedError == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(tc.ExpectedError))
+			}
+		},
+		Entry("No failure domain label", testCaseFailureDomain{
+			FailureDomain: pointer.StringPtr("rack1"),
+		}),
+		Entry("No failure domain", testCaseFailureDomain{
+			FailureDomainLabel: "rack",
+			HostLabels:         map[string]string{"rack": "rack2"},
+		}),
+		Entry("Matching failure domain", testCaseFailureDomain{
+			FailureDomainLabel: "rack",
+			FailureDomain:      pointer.StringPtr("rack1"),
+			HostLabels:         map[string]string{"rack": "rack1"},
+		}),
+		Entry("Other failure domain", testCaseFailureDomain{
+			FailureDomainLabel: "rack",
+			FailureDomain:      pointer.StringPtr("rack1"),
+			HostLabels:         map[string]string{"rack": "rack2"},
+			ExpectedError:      "failure domain \"rack2\" found, \"rack1\" required",
+		}),
+		Entry("Label not set", testCaseFailureDomain{
+			FailureDomainLabel: "rack",
+			FailureDomain:      pointer.StringPtr("rack1"),
+			ExpectedError:      "label \"rack\" not set",
+		}),
+	)
+})
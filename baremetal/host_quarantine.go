@@ -0,0 +1,169 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// HostProvisioningFailuresAnnotation is the annotation counting the
+	// provisioning failures of a BareMetalHost
+	HostProvisioningFailuresAnnotation = "metal3.io/provisioning-failures"
+	// HostLastFailedConsumerAnnotation is the annotation containing the UID
+	// of the last BareMetalMachine for which a provisioning failure of the
+	// BareMetalHost was counted, so that each failure is only counted once
+	HostLastFailedConsumerAnnotation = "metal3.io/last-failed-consumer"
+	// HostQuarantineLabel is the label set on the BareMetalHosts that are
+	// quarantined and will not be chosen for any BareMetalMachine
+	HostQuarantineLabel = "metal3.io/quarantined"
+	// HostReleaseQuarantineAnnotation is the annotation an operator sets on
+	// a quarantined BareMetalHost to release it from quarantine
+	HostReleaseQuarantineAnnotation = "metal3.io/release-quarantine"
+
+	eventSourceComponent = "metal3-cluster-api-provider"
+)
+
+// hostProvisioningFailures returns the number of provisioning failures
+// recorded on the host. An invalid value is considered as no failure.
+func hostProvisioningFailures(host *bmh.BareMetalHost) int {
+	failures, err := strconv.Atoi(host.Annotations[HostProvisioningFailuresAnnotation])
+	if err != nil || failures < 0 {
+		return 0
+	}
+	return failures
+}
+
+// checkQuarantine returns an error giving the reason why the host is
+// quarantined, or nil if it is not.
+func checkQuarantine(threshold int, host *bmh.BareMetalHost) error {
+	if _, ok := host.Labels[HostQuarantineLabel]; ok {
+		return errors.New("host is quarantined")
+	}
+	failures := hostProvisioningFailures(host)
+	if threshold > 0 && failures >= threshold {
+		return errors.Errorf("%d provisioning failures found, quarantine threshold is %d",
+			failures, threshold,
+		)
+	}
+	return nil
+}
+
+// recordHostFailure counts the provisioning failure of the host, if any, and
+// quarantines the host once the threshold is reached. A failure is counted
+// only once for each BareMetalMachine.
+func (m *MachineManager) recordHostFailure(ctx context.Context, host *bmh.BareMetalHost) error {
+	if host.Status.ErrorType != bmh.ProvisioningError {
+		return nil
+	}
+	consumerUID := string(m.BareMetalMachine.UID)
+	lastConsumerUID, ok := host.Annotations[HostLastFailedConsumerAnnotation]
+	if ok && lastConsumerUID == consumerUID {
+		return nil
+	}
+
+	failures := hostProvisioningFailures(host) + 1
+	if host.Annotations == nil {
+		host.Annotations = make(map[string]string)
+	}
+	host.Annotations[HostProvisioningFailuresAnnotation] = strconv.Itoa(failures)
+	host.Annotations[HostLastFailedConsumerAnnotation] = consumerUID
+	m.Log.Info("Recording provisioning failure of host", "host", host.Name,
+		"failures", failures,
+	)
+
+	quarantine := m.QuarantineThreshold > 0 && failures >= m.QuarantineThreshold &&
+		host.Labels[HostQuarantineLabel] == ""
+	if quarantine {
+		if host.Labels == nil {
+			host.Labels = make(map[string]string)
+		}
+		host.Labels[HostQuarantineLabel] = "true"
+	}
+
+	if err := m.client.Update(ctx, host); err != nil {
+		return errors.Wrap(err, "failed to record the provisioning failure of the host")
+	}
+
+	if quarantine {
+		m.Log.Info("Quarantining host", "host", host.Name, "failures", failures)
+		return m.createHostEvent(ctx, host, corev1.EventTypeWarning, "Quarantined",
+			fmt.Sprintf("Host quarantined after %d provisioning failures, set the %s annotation to release it",
+				failures, HostReleaseQuarantineAnnotation,
+			),
+		)
+	}
+	return nil
+}
+
+// releaseQuarantine releases the host from quarantine if an operator set the
+// release annotation on it, and resets its provisioning failures count. It
+// returns whether the host was released.
+func (m *MachineManager) releaseQuarantine(ctx context.Context, host *bmh.BareMetalHost) (bool, error) {
+	if _, ok := host.Annotations[HostReleaseQuarantineAnnotation]; !ok {
+		return false, nil
+	}
+
+	delete(host.Annotations, HostReleaseQuarantineAnnotation)
+	delete(host.Annotations, HostProvisioningFailuresAnnotation)
+	delete(host.Annotations, HostLastFailedConsumerAnnotation)
+	delete(host.Labels, HostQuarantineLabel)
+	if err := m.client.Update(ctx, host); err != nil {
+		return false, errors.Wrap(err, "failed to release the host from quarantine")
+	}
+
+	m.Log.Info("Host released from quarantine", "host", host.Name)
+	return true, m.createHostEvent(ctx, host, corev1.EventTypeNormal,
+		"ReleasedFromQuarantine", "Host released from quarantine",
+	)
+}
+
+// releaseQuarantinedHosts releases from quarantine the hosts of the given
+// namespaces on which an operator set the release annotation, so that they
+// can be chosen again.
+func (m *MachineManager) releaseQuarantinedHosts(ctx context.Context, namespaces []string) error {
+	hosts, err := m.listHosts(ctx, namespaces)
+	if err != nil {
+		return err
+	}
+	for i := range hosts.Items {
+		_, err = m.releaseQuarantine(ctx, &hosts.Items[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createHostEvent creates an event related to the host
+func (m *MachineManager) createHostEvent(ctx context.Context,
+	host *bmh.BareMetalHost, eventType, reason, message string,
+) error {
+	event := host.NewEvent(reason, message)
+	event.Type = eventType
+	event.Source.Component = eventSourceComponent
+	if err := m.client.Create(ctx, &event); err != nil {
+		return errors.Wrapf(err, "failed to create %s event", reason)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Host quarantine", func() {
+
+	type testCaseCheckQuarantine struct {
+		Threshold     int
+		Labels        map[string]string
+		Annotations   map[string]string
+		ExpectedError string
+	}
+
+	DescribeTable("Test checkQuarantine",
+		func(tc testCaseCheckQuarantine) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      tc.Labels,
+					Annotations: tc.Annotations,
+				},
+			}
+			err := checkQuarantine(tc.Threshold, host)
+			if tc.ExpectedError == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(tc.ExpectedError))
+			}
+		},
+		Entry("No failures", testCaseCheckQuarantine{
+			Threshold: 3,
+		}),
+		Entry("Below threshold", testCaseCheckQuarantine{
+			Threshold:   3,
+			Annotations: map[string]string{HostProvisioningFailuresAnnotation: "2"},
+		}),
+		Entry("Threshold reached", testCaseCheckQuarantine{
+			Threshold:     3,
+			Annotations:   map[string]string{HostProvisioningFailuresAnnotation: "3"},
+			ExpectedError: "3 provisioning failures found, quarantine threshold is 3",
+		}),
+		Entry("Quarantine disabled", testCaseCheckQuarantine{
+			Threshold:   0,
+			Annotations: map[string]string{HostProvisioningFailuresAnnotation: "5"},
+		}),
+		Entry("Invalid count", testCaseCheckQuarantine{
+			Threshold:   3,
+			Annotations: map[string]string{HostProvisioningFailuresAnnotation: "abc"},
+		}),
+		Entry("Quarantine label", testCaseCheckQuarantine{
+			Threshold:     0,
+			Labels:        map[string]string{HostQuarantineLabel: "true"},
+			ExpectedError: "host is quarantined",
+		}),
+	)
+
+	type testCaseRecordHostFailure struct {
+		ErrorType           bmh.ErrorType
+		Annotations         map[string]string
+		ExpectedFailures    string
+		ExpectedQuarantined bool
+	}
+
+	DescribeTable("Test recordHostFailure",
+		func(tc testCaseRecordHostFailure) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "myhost",
+					Namespace:   "myns",
+					Annotations: tc.Annotations,
+				},
+				Status: bmh.BareMetalHostStatus{
+					ErrorType: tc.ErrorType,
+				},
+			}
+			bmMachine := &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: "myns",
+					UID:       "bmm-uid",
+				},
+			}
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm(), host)
+			machineMgr, err := NewMachineManager(c, nil, nil, nil, bmMachine,
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			machineMgr.QuarantineThreshold = 3
+
+			err = machineMgr.recordHostFailure(context.TODO(), host)
+			Expect(err).NotTo(HaveOccurred())
+
+			savedHost := bmh.BareMetalHost{}
+			err = c.Get(context.TODO(),
+				client.ObjectKey{Name: host.Name, Namespace: host.Namespace},
+				&savedHost,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(savedHost.Annotations[HostProvisioningFailuresAnnotation]).To(
+				Equal(tc.ExpectedFailures),
+			)
+			_, quarantined := savedHost.Labels[HostQuarantineLabel]
+			Expect(quarantined).To(Equal(tc.ExpectedQuarantined))
+
+			events := corev1.EventList{}
+			Expect(c.List(context.TODO(), &events)).To(Succeed())
+			if tc.ExpectedQuarantined {
+				Expect(events.Items).To(HaveLen(1))
+				Expect(events.Items[0].Reason).To(Equal("Quarantined"))
+				Expect(events.Items[0].Type).To(Equal(corev1.EventTypeWarning))
+			} else {
+				Expect(events.Items).To(BeEmpty())
+			}
+		},
+		Entry("No error", testCaseRecordHostFailure{}),
+		Entry("Other error", testCaseRecordHostFailure{
+			ErrorType: bmh.RegistrationError,
+		}),
+		Entry("First failure", testCaseRecordHostFailure{
+			ErrorType:        bmh.ProvisioningError,
+			ExpectedFailures: "1",
+		}),
+		Entry("Failure already counted", testCaseRecordHostFailure{
+			ErrorType: bmh.ProvisioningError,
+			Annotations: map[string]string{
+				HostProvisioningFailuresAnnotation: "1",
+				HostLastFailedConsumerAnnotation:   "bmm-uid",
+			},
+			ExpectedFailures: "1",
+		}),
+		Entry("Threshold reached", testCaseRecordHostFailure{
+			ErrorType: bmh.ProvisioningError,
+			Annotations: map[string]string{
+				HostProvisioningFailuresAnnotation: "2",
+				HostLastFailedConsumerAnnotation:   "other-uid",
+			},
+			ExpectedFailures:    "3",
+			ExpectedQuarantined: true,
+		}),
+	)
+
+	type testCaseReleaseQuarantine struct {
+		Annotations     map[string]string
+		ExpectedRelease bool
+	}
+
+	DescribeTable("Test releaseQuarantine",
+		func(tc testCaseReleaseQuarantine) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "myhost",
+					Namespace:   "myns",
+					Labels:      map[string]string{HostQuarantineLabel: "true"},
+					Annotations: tc.Annotations,
+				},
+			}
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm(), host)
+			machineMgr, err := NewMachineManager(c, nil, nil, nil,
+				&capm3.BareMetalMachine{}, klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			released, err := machineMgr.releaseQuarantine(context.TODO(), host)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(Equal(tc.ExpectedRelease))
+
+			savedHost := bmh.BareMetalHost{}
+			err = c.Get(context.TODO(),
+				client.ObjectKey{Name: host.Name, Namespace: host.Namespace},
+				&savedHost,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			err = checkQuarantine(3, &savedHost)
+			if tc.ExpectedRelease {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(savedHost.Annotations).NotTo(
+					HaveKey(HostReleaseQuarantineAnnotation),
+				)
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("Not released", testCaseReleaseQuarantine{
+			Annotations: map[string]string{
+				HostProvisioningFailuresAnnotation: "3",
+			},
+		}),
+		Entry("Released", testCaseReleaseQuarantine{
+			Annotations: map[string]string{
+				HostProvisioningFailuresAnnotation: "3",
+				HostLastFailedConsumerAnnotation:   "bmm-uid",
+				HostReleaseQuarantineAnnotation:    "",
+			},
+			ExpectedRelease: true,
+		}),
+	)
+
+	It("releases the quarantined hosts before choosing one", func() {
+		quarantinedHost := &bmh.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "quarantinedHost",
+				Namespace: "myns",
+				Labels:    map[string]string{HostQuarantineLabel: "true"},
+			},
+		}
+		releasedHost := &bmh.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "releasedHost",
+				Namespace:   "myns",
+				Labels:      map[string]string{HostQuarantineLabel: "true"},
+				Annotations: map[string]string{HostReleaseQuarantineAnnotation: ""},
+			},
+		}
+		c := fakeclient.NewFakeClientWithScheme(setupSchemeMm(), quarantinedHost,
+			releasedHost,
+		)
+		machineMgr, err := NewMachineManager(c, nil, nil, nil,
+			&capm3.BareMetalMachine{}, klogr.New(),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = machineMgr.releaseQuarantinedHosts(context.TODO(), []string{"myns"})
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, host := range []*bmh.BareMetalHost{quarantinedHost, releasedHost} {
+			savedHost := bmh.BareMetalHost{}
+			err = c.Get(context.TODO(),
+				client.ObjectKey{Name: host.Name, Namespace: host.Namespace},
+				&savedHost,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			_, quarantined := savedHost.Labels[HostQuarantineLabel]
+			Expect(quarantined).To(Equal(host == quarantinedHost))
+		}
+	})
+})
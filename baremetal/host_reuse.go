@@ -0,0 +1,119 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+const (
+	// HostLastConsumerAnnotation is the annotation containing the
+	// namespace/name of the last Machine that used the BareMetalHost
+	HostLastConsumerAnnotation = "metal3.io/last-consumer"
+	// HostLastConsumerMachineSetAnnotation is the annotation containing the
+	// namespace/name of the MachineSet of the last Machine that used the
+	// BareMetalHost
+	HostLastConsumerMachineSetAnnotation = "metal3.io/last-consumer-machineset"
+	// HostLastConsumerDeploymentAnnotation is the annotation containing the
+	// namespace/name of the MachineDeployment of the last Machine that used
+	// the BareMetalHost
+	HostLastConsumerDeploymentAnnotation = "metal3.io/last-consumer-deployment"
+)
+
+// machineKey returns the namespace/name of the Machine
+func (m *MachineManager) machineKey() string {
+	return m.Machine.Namespace + "/" + m.Machine.Name
+}
+
+// machineSetKey returns the namespace/name of the MachineSet owning the
+// Machine, or an empty string if the Machine is not owned by a MachineSet.
+func (m *MachineManager) machineSetKey() string {
+	for _, ref := range m.Machine.OwnerReferences {
+		if ref.Kind == "MachineSet" {
+			return m.Machine.Namespace + "/" + ref.Name
+		}
+	}
+	return ""
+}
+
+// machineDeploymentKey returns the namespace/name of the MachineDeployment
+// of the Machine, or an empty string if the Machine does not belong to a
+// MachineDeployment. A rollout creates a new MachineSet, but keeps the
+// MachineDeployment.
+func (m *MachineManager) machineDeploymentKey() string {
+	if name := m.Machine.Labels[capi.MachineDeploymentLabelName]; name != "" {
+		return m.Machine.Namespace + "/" + name
+	}
+	return ""
+}
+
+// recordLastConsumer sets the annotations recording the Machine, and its
+// MachineSet and MachineDeployment if any, as the last consumer of the host.
+// The host is not updated.
+func (m *MachineManager) recordLastConsumer(host *bmh.BareMetalHost) {
+	if host.Annotations == nil {
+		host.Annotations = make(map[string]string)
+	}
+	host.Annotations[HostLastConsumerAnnotation] = m.machineKey()
+	if machineSet := m.machineSetKey(); machineSet != "" {
+		host.Annotations[HostLastConsumerMachineSetAnnotation] = machineSet
+	} else {
+		delete(host.Annotations, HostLastConsumerMachineSetAnnotation)
+	}
+	if deployment := m.machineDeploymentKey(); deployment != "" {
+		host.Annotations[HostLastConsumerDeploymentAnnotation] = deployment
+	} else {
+		delete(host.Annotations, HostLastConsumerDeploymentAnnotation)
+	}
+}
+
+// lastUsedHost returns the host last used by a Machine of the same name or,
+// if there is none, of the same MachineSet or, failing that, of the same
+// MachineDeployment. It returns nil if there is no such host or if the
+// BareMetalMachine does not reuse hosts.
+func (m *MachineManager) lastUsedHost(hosts []*bmh.BareMetalHost) *bmh.BareMetalHost {
+	if !m.BareMetalMachine.Spec.ReuseLastHost {
+		return nil
+	}
+
+	machine := m.machineKey()
+	for _, host := range hosts {
+		if host.Annotations[HostLastConsumerAnnotation] == machine {
+			return host
+		}
+	}
+
+	if machineSet := m.machineSetKey(); machineSet != "" {
+		for _, host := range hosts {
+			if host.Annotations[HostLastConsumerMachineSetAnnotation] == machineSet {
+				return host
+			}
+		}
+	}
+
+	deployment := m.machineDeploymentKey()
+	if deployment == "" {
+		return nil
+	}
+	for _, host := range hosts {
+		if host.Annotations[HostLastConsumerDeploymentAnnotation] == deployment {
+			return host
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/klogr"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+var _ = Describe("Host reuse", func() {
+
+	machineInSet := func(name string) *capi.Machine {
+		return &capi.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "myns",
+				Labels: map[string]string{
+					capi.MachineDeploymentLabelName: "workers-md",
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "MachineSet", Name: "workers"},
+				},
+			},
+		}
+	}
+
+	hostUsedByDeployment := func(name, machine, machineSet, deployment string,
+	) *bmh.BareMetalHost {
+		return &bmh.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "myns",
+				Annotations: map[string]string{
+					HostLastConsumerAnnotation:           machine,
+					HostLastConsumerMachineSetAnnotation: machineSet,
+					HostLastConsumerDeploymentAnnotation: deployment,
+				},
+			},
+		}
+	}
+
+	hostUsedBy := func(name, machine, machineSet string) *bmh.BareMetalHost {
+		host := &bmh.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "myns",
+				Annotations: map[string]string{},
+			},
+		}
+		if machine != "" {
+			host.Annotations[HostLastConsumerAnnotation] = machine
+		}
+		if machineSet != "" {
+			host.Annotations[HostLastConsumerMachineSetAnnotation] = machineSet
+		}
+		return host
+	}
+
+	It("Records the last consumer of the host", func() {
+		machineMgr, err := NewMachineManager(nil, nil, nil,
+			machineInSet("worker-0"), &capm3.BareMetalMachine{}, klogr.New(),
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		host := &bmh.BareMetalHost{}
+		machineMgr.recordLastConsumer(host)
+		Expect(host.Annotations).To(Equal(map[string]string{
+			HostLastConsumerAnnotation:           "myns/worker-0",
+			HostLastConsumerMachineSetAnnotation: "myns/workers",
+			HostLastConsumerDeploymentAnnotation: "myns/workers-md",
+		}))
+
+		// A Machine without MachineSet and MachineDeployment clears the
+		// previous ones
+		machineMgr.Machine.OwnerReferences = nil
+		machineMgr.Machine.Labels = nil
+		machineMgr.recordLastConsumer(host)
+		Expect(host.Annotations).To(Equal(map[string]string{
+			HostLastConsumerAnnotation: "myns/worker-0",
+		}))
+	})
+
+	type testCaseLastUsedHost struct {
+		ReuseLastHost    bool
+		Hosts            []*bmh.BareMetalHost
+		ExpectedHostName string
+	}
+
+	DescribeTable("Test lastUsedHost",
+		func(tc testCaseLastUsedHost) {
+			bmMachine := &capm3.BareMetalMachine{
+				Spec: capm3.BareMetalMachineSpec{
+					ReuseLastHost: tc.ReuseLastHost,
+				},
+			}
+			machineMgr, err := NewMachineManager(nil, nil, nil,
+				machineInSet("worker-0"), bmMachine, klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			host := machineMgr.lastUsedHost(tc.Hosts)
+			if tc.ExpectedHostName == "" {
+				Expect(host).To(BeNil())
+			} else {
+				Expect(host).NotTo(BeNil())
+				Expect(host.Name).To(Equal(tc.ExpectedHostName))
+			}
+		},
+		Entry("Reuse disabled", testCaseLastUsedHost{
+			Hosts: []*bmh.BareMetalHost{
+				hostUsedBy("host0", "myns/worker-0", "myns/workers"),
+			},
+		}),
+		Entry("Host used by the same machine", testCaseLastUsedHost{
+			ReuseLastHost: true,
+			Hosts: []*bmh.BareMetalHost{
+				hostUsedBy("host0", "myns/worker-1", "myns/workers"),
+				hostUsedBy("host1", "myns/worker-0", "myns/workers"),
+			},
+			ExpectedHostName: "host1",
+		}),
+		Entry("Host used by the same MachineSet", testCaseLastUsedHost{
+			ReuseLastHost: true,
+			Hosts: []*bmh.BareMetalHost{
+				hostUsedBy("host0", "myns/other-0", "myns/other"),
+				hostUsedBy("host1", "myns/worker-1", "myns/workers"),
+			},
+			ExpectedHostName: "host1",
+		}),
+		Entry("Host used by a previous MachineSet of the MachineDeployment", testCaseLastUsedHost{
+			ReuseLastHost: true,
+			Hosts: []*bmh.BareMetalHost{
+				hostUsedByDeployment("host0", "myns/other-0", "myns/other",
+					"myns/other-md",
+				),
+				hostUsedByDeployment("host1", "myns/workers-old-0",
+					"myns/workers-old", "myns/workers-md",
+				),
+			},
+			ExpectedHostName: "host1",
+		}),
+		Entry("Host used by the same MachineSet before the MachineDeployment", testCaseLastUsedHost{
+			ReuseLastHost: true,
+			Hosts: []*bmh.BareMetalHost{
+				hostUsedByDeployment("host0", "myns/workers-old-0",
+					"myns/workers-old", "myns/workers-md",
+				),
+				hostUsedByDeployment("host1", "myns/worker-1", "myns/workers",
+					"myns/workers-md",
+				),
+			},
+			ExpectedHostName: "host1",
+		}),
+		Entry("Machine of the same name in another namespace", testCaseLastUsedHost{
+			ReuseLastHost: true,
+			Hosts: []*bmh.BareMetalHost{
+				hostUsedBy("host0", "otherns/worker-0", "otherns/workers"),
+			},
+		}),
+		Entry("No host used before", testCaseLastUsedHost{
+			ReuseLastHost: true,
+			Hosts: []*bmh.BareMetalHost{
+				hostUsedBy("host0", "", ""),
+			},
+		}),
+	)
+})
@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// upgradeImage drives the upgrade of the image of the host when the image of
+// the BareMetalMachine changed and the upgrade policy allows it. The host is
+// first drained, if enabled, and deprovisioned by removing its image, then
+// provisioned again by setHostSpec once it is ready, keeping its user data.
+// The Node is uncordoned once the upgrade completed. The progress is recorded
+// in the status of the BareMetalMachine. The host is modified but not
+// updated.
+func (m *MachineManager) upgradeImage(ctx context.Context, host *bmh.BareMetalHost) error {
+	if m.BareMetalMachine.Spec.ImageUpgradePolicy != capm3.ImageUpgradePolicyReprovision {
+		return nil
+	}
+	image, err := m.hostImage(host)
+	if err != nil {
+		return err
+	}
+	upgrade := m.BareMetalMachine.Status.ImageUpgrade
+
+	switch {
+	case upgrade != nil && upgrade.Phase == capm3.ImageUpgradeDeprovisioning:
+		if host.Status.Provisioning.State != bmh.StateReady {
+			m.Log.Info("Waiting for the host to be deprovisioned", "host", host.Name)
+			return nil
+		}
+		m.Log.Info("Provisioning host with the new image", "host", host.Name,
+			"image", image.URL,
+		)
+		upgrade.Phase = capm3.ImageUpgradeProvisioning
+		upgrade.Image = image.URL
+
+	case upgrade != nil && upgrade.Phase == capm3.ImageUpgradeProvisioning:
+		if host.Status.Provisioning.State != bmh.StateProvisioned ||
+			host.Status.Provisioning.Image.URL != upgrade.Image {
+			return nil
+		}
+		m.Log.Info("Image upgrade completed", "host", host.Name,
+			"image", upgrade.Image,
+		)
+		if err := m.uncordonNode(ctx, host); err != nil {
+			return err
+		}
+		now := metav1.Now()
+		upgrade.Phase = capm3.ImageUpgradeCompleted
+		upgrade.CompletedAt = &now
+		// the Node must be drained again before the next deprovisioning
+		m.BareMetalMachine.Status.NodeDrain = nil
+
+	case host.Spec.Image != nil && (host.Spec.Image.URL != image.URL ||
+		host.Spec.Image.Checksum != image.Checksum):
+		// drain the Node first, if enabled
+		if err := m.drainNode(ctx, host); err != nil {
+			return err
+		}
+		m.Log.Info("Image changed, deprovisioning host", "host", host.Name,
+			"image", image.URL,
+		)
+		now := metav1.Now()
+		m.BareMetalMachine.Status.ImageUpgrade = &capm3.ImageUpgradeStatus{
+			Phase:     capm3.ImageUpgradeDeprovisioning,
+			Image:     image.URL,
+			StartedAt: &now,
+		}
+		host.Spec.Image = nil
+	}
+	return nil
+}
+
+// isDeprovisioningForUpgrade returns true while the host is deprovisioned
+// for an image upgrade, in which case its image must not be set.
+func (m *MachineManager) isDeprovisioningForUpgrade() bool {
+	upgrade := m.BareMetalMachine.Status.ImageUpgrade
+	return upgrade != nil && upgrade.Phase == capm3.ImageUpgradeDeprovisioning
+}
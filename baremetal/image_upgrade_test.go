@@ -0,0 +1,280 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientfake "k8s.io/client-go/kubernetes/fake"
+	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/klogr"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Image upgrade", func() {
+
+	newImage := capm3.Image{
+		URL:      "http://172.22.0.1/images/new.qcow2",
+		Checksum: "http://172.22.0.1/images/new.qcow2.md5sum",
+	}
+	oldImage := &bmh.Image{
+		URL:      "http://172.22.0.1/images/old.qcow2",
+		Checksum: "http://172.22.0.1/images/old.qcow2.md5sum",
+	}
+
+	type testCaseUpgradeImage struct {
+		Policy          capm3.ImageUpgradePolicy
+		Upgrade         *capm3.ImageUpgradeStatus
+		HostImage       *bmh.Image
+		HostState       bmh.ProvisioningState
+		ProvisionedURL  string
+		NodeDrain       *capm3.NodeDrainPolicy
+		DrainStatus     *capm3.NodeDrainStatus
+		NodeCordoned    bool
+		Pods            []runtime.Object
+		ExpectRequeue   bool
+		ExpectedPhase   capm3.ImageUpgradePhase
+		ExpectedHostURL string
+		ExpectCordoned  bool
+	}
+
+	DescribeTable("Test upgradeImage",
+		func(tc testCaseUpgradeImage) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "myhost",
+					Namespace: "myns",
+					UID:       "host-uid",
+				},
+				Spec: bmh.BareMetalHostSpec{
+					Image: tc.HostImage,
+					UserData: &corev1.SecretReference{
+						Name:      "mybmmachine-user-data",
+						Namespace: "myns",
+					},
+				},
+				Status: bmh.BareMetalHostStatus{
+					Provisioning: bmh.ProvisionStatus{
+						State: tc.HostState,
+						Image: bmh.Image{URL: tc.ProvisionedURL},
+					},
+				},
+			}
+			bmMachine := &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: "myns",
+				},
+				Spec: capm3.BareMetalMachineSpec{
+					Image:              newImage,
+					ImageUpgradePolicy: tc.Policy,
+					NodeDrain:          tc.NodeDrain,
+					UserData: &corev1.SecretReference{
+						Name:      "mybmmachine-user-data",
+						Namespace: "myns",
+					},
+				},
+				Status: capm3.BareMetalMachineStatus{
+					ImageUpgrade: tc.Upgrade,
+					NodeDrain:    tc.DrainStatus,
+				},
+			}
+			objects := append(tc.Pods, &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "node-0",
+					Labels: map[string]string{"metal3.io/uuid": "host-uid"},
+				},
+				Spec: corev1.NodeSpec{
+					Unschedulable: tc.NodeCordoned,
+				},
+			})
+			clientset := clientfake.NewSimpleClientset(objects...)
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm(), host)
+			machineMgr, err := NewMachineManager(c, newCluster(clusterName), nil,
+				newMachine("mymachine", "mybmmachine", nil), bmMachine,
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			machineMgr.RemoteClientGetter = func(ctx context.Context, c client.Client,
+				cluster *capi.Cluster,
+			) (clientcorev1.CoreV1Interface, error) {
+				return clientset.CoreV1(), nil
+			}
+
+			err = machineMgr.upgradeImage(context.TODO(), host)
+			if tc.ExpectRequeue {
+				_, ok := errors.Cause(err).(HasRequeueAfterError)
+				Expect(ok).To(BeTrue())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(machineMgr.setHostSpec(context.TODO(), host)).To(Succeed())
+
+			node, err := clientset.CoreV1().Nodes().Get("node-0", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(node.Spec.Unschedulable).To(Equal(tc.ExpectCordoned))
+			if tc.ExpectedPhase == capm3.ImageUpgradeCompleted {
+				// the Node is drained again on the next deprovisioning
+				Expect(bmMachine.Status.NodeDrain).To(BeNil())
+			}
+
+			if tc.ExpectedPhase == "" {
+				Expect(bmMachine.Status.ImageUpgrade).To(BeNil())
+			} else {
+				Expect(bmMachine.Status.ImageUpgrade).NotTo(BeNil())
+				Expect(bmMachine.Status.ImageUpgrade.Phase).To(Equal(tc.ExpectedPhase))
+				Expect(bmMachine.Status.ImageUpgrade.StartedAt).NotTo(BeNil())
+				Expect(bmMachine.Status.ImageUpgrade.CompletedAt == nil).To(
+					Equal(tc.ExpectedPhase != capm3.ImageUpgradeCompleted),
+				)
+			}
+
+			savedHost := bmh.BareMetalHost{}
+			err = c.Get(context.TODO(),
+				client.ObjectKey{Name: host.Name, Namespace: host.Namespace},
+				&savedHost,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			if tc.ExpectedHostURL == "" {
+				Expect(savedHost.Spec.Image).To(BeNil())
+			} else {
+				Expect(savedHost.Spec.Image).NotTo(BeNil())
+				Expect(savedHost.Spec.Image.URL).To(Equal(tc.ExpectedHostURL))
+			}
+			// The user data is kept
+			Expect(savedHost.Spec.UserData).NotTo(BeNil())
+		},
+		Entry("Image change ignored without policy", testCaseUpgradeImage{
+			HostImage:       oldImage,
+			HostState:       bmh.StateProvisioned,
+			ProvisionedURL:  oldImage.URL,
+			ExpectedHostURL: oldImage.URL,
+		}),
+		Entry("Image unchanged", testCaseUpgradeImage{
+			Policy:          capm3.ImageUpgradePolicyReprovision,
+			HostImage:       &bmh.Image{URL: newImage.URL, Checksum: newImage.Checksum},
+			HostState:       bmh.StateProvisioned,
+			ProvisionedURL:  newImage.URL,
+			ExpectedHostURL: newImage.URL,
+		}),
+		Entry("Image changed, deprovisioning", testCaseUpgradeImage{
+			Policy:         capm3.ImageUpgradePolicyReprovision,
+			HostImage:      oldImage,
+			HostState:      bmh.StateProvisioned,
+			ProvisionedURL: oldImage.URL,
+			ExpectedPhase:  capm3.ImageUpgradeDeprovisioning,
+		}),
+		Entry("Waiting for the host to be deprovisioned", testCaseUpgradeImage{
+			Policy: capm3.ImageUpgradePolicyReprovision,
+			Upgrade: &capm3.ImageUpgradeStatus{
+				Phase:     capm3.ImageUpgradeDeprovisioning,
+				Image:     newImage.URL,
+				StartedAt: &metav1.Time{},
+			},
+			HostState:      bmh.StateDeprovisioning,
+			ProvisionedURL: oldImage.URL,
+			ExpectedPhase:  capm3.ImageUpgradeDeprovisioning,
+		}),
+		Entry("Host deprovisioned, provisioning", testCaseUpgradeImage{
+			Policy: capm3.ImageUpgradePolicyReprovision,
+			Upgrade: &capm3.ImageUpgradeStatus{
+				Phase:     capm3.ImageUpgradeDeprovisioning,
+				Image:     newImage.URL,
+				StartedAt: &metav1.Time{},
+			},
+			HostState:       bmh.StateReady,
+			ExpectedPhase:   capm3.ImageUpgradeProvisioning,
+			ExpectedHostURL: newImage.URL,
+		}),
+		Entry("Waiting for the host to be provisioned", testCaseUpgradeImage{
+			Policy: capm3.ImageUpgradePolicyReprovision,
+			Upgrade: &capm3.ImageUpgradeStatus{
+				Phase:     capm3.ImageUpgradeProvisioning,
+				Image:     newImage.URL,
+				StartedAt: &metav1.Time{},
+			},
+			HostImage:       &bmh.Image{URL: newImage.URL, Checksum: newImage.Checksum},
+			HostState:       bmh.StateProvisioning,
+			ExpectedPhase:   capm3.ImageUpgradeProvisioning,
+			ExpectedHostURL: newImage.URL,
+		}),
+		Entry("Host provisioned, upgrade completed", testCaseUpgradeImage{
+			Policy: capm3.ImageUpgradePolicyReprovision,
+			Upgrade: &capm3.ImageUpgradeStatus{
+				Phase:     capm3.ImageUpgradeProvisioning,
+				Image:     newImage.URL,
+				StartedAt: &metav1.Time{},
+			},
+			HostImage:       &bmh.Image{URL: newImage.URL, Checksum: newImage.Checksum},
+			HostState:       bmh.StateProvisioned,
+			ProvisionedURL:  newImage.URL,
+			ExpectedPhase:   capm3.ImageUpgradeCompleted,
+			ExpectedHostURL: newImage.URL,
+		}),
+		Entry("Image changed, draining the node", testCaseUpgradeImage{
+			Policy:          capm3.ImageUpgradePolicyReprovision,
+			NodeDrain:       &capm3.NodeDrainPolicy{},
+			HostImage:       oldImage,
+			HostState:       bmh.StateProvisioned,
+			ProvisionedURL:  oldImage.URL,
+			Pods:            []runtime.Object{newDrainPod("pod-0", "ReplicaSet", nil)},
+			ExpectRequeue:   true,
+			ExpectedHostURL: oldImage.URL,
+			ExpectCordoned:  true,
+		}),
+		Entry("Image changed, node drained, deprovisioning", testCaseUpgradeImage{
+			Policy:         capm3.ImageUpgradePolicyReprovision,
+			NodeDrain:      &capm3.NodeDrainPolicy{},
+			HostImage:      oldImage,
+			HostState:      bmh.StateProvisioned,
+			ProvisionedURL: oldImage.URL,
+			ExpectedPhase:  capm3.ImageUpgradeDeprovisioning,
+			ExpectCordoned: true,
+		}),
+		Entry("Host provisioned, node uncordoned", testCaseUpgradeImage{
+			Policy:    capm3.ImageUpgradePolicyReprovision,
+			NodeDrain: &capm3.NodeDrainPolicy{},
+			DrainStatus: &capm3.NodeDrainStatus{
+				Node:        "node-0",
+				CompletedAt: &metav1.Time{},
+			},
+			NodeCordoned: true,
+			Upgrade: &capm3.ImageUpgradeStatus{
+				Phase:     capm3.ImageUpgradeProvisioning,
+				Image:     newImage.URL,
+				StartedAt: &metav1.Time{},
+			},
+			HostImage:       &bmh.Image{URL: newImage.URL, Checksum: newImage.Checksum},
+			HostState:       bmh.StateProvisioned,
+			ProvisionedURL:  newImage.URL,
+			ExpectedPhase:   capm3.ImageUpgradeCompleted,
+			ExpectedHostURL: newImage.URL,
+		}),
+	)
+})
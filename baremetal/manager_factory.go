@@ -29,21 +29,32 @@ type ManagerFactoryInterface interface {
 		clusterLog logr.Logger) (ClusterManagerInterface, error)
 	NewMachineManager(*capi.Cluster, *capm3.BareMetalCluster, *capi.Machine,
 		*capm3.BareMetalMachine, logr.Logger) (MachineManagerInterface, error)
+	NewRemediationManager(*capm3.BareMetalRemediation, *capi.Machine,
+		logr.Logger) (RemediationManagerInterface, error)
 }
 
-// ManagerFactory only contains a client
+// ManagerFactory contains a client and the configuration of the managers
 type ManagerFactory struct {
-	client client.Client
+	client        client.Client
+	machineConfig MachineManagerConfig
 }
 
 // NewManagerFactory returns a new factory.
-func NewManagerFactory(client client.Client) ManagerFactory {
-	return ManagerFactory{client: client}
+func NewManagerFactory(client client.Client, machineConfig MachineManagerConfig) ManagerFactory {
+	return ManagerFactory{
+		client:        client,
+		machineConfig: machineConfig,
+	}
 }
 
 // NewClusterManager creates a new ClusterManager
 func (f ManagerFactory) NewClusterManager(cluster *capi.Cluster, capm3Cluster *capm3.BareMetalCluster, clusterLog logr.Logger) (ClusterManagerInterface, error) {
-	return NewClusterManager(f.client, cluster, capm3Cluster, clusterLog)
+	clusterMgr, err := NewClusterManager(f.client, cluster, capm3Cluster, clusterLog)
+	if err != nil {
+		return nil, err
+	}
+	clusterMgr.(*ClusterManager).AllowedHostNamespaces = f.machineConfig.AllowedHostNamespaces
+	return clusterMgr, nil
 }
 
 // NewMachineManager creates a new MachineManager
@@ -51,6 +62,26 @@ func (f ManagerFactory) NewMachineManager(capiCluster *capi.Cluster,
 	capm3Cluster *capm3.BareMetalCluster,
 	capiMachine *capi.Machine, capm3Machine *capm3.BareMetalMachine,
 	machineLog logr.Logger) (MachineManagerInterface, error) {
-	return NewMachineManager(f.client, capiCluster, capm3Cluster, capiMachine,
-		capm3Machine, machineLog)
+	machineMgr, err := NewMachineManager(f.client, capiCluster, capm3Cluster,
+		capiMachine, capm3Machine, machineLog,
+	)
+	if err != nil {
+		return nil, err
+	}
+	machineMgr.MachineManagerConfig = f.machineConfig
+	return machineMgr, nil
+}
+
+// NewRemediationManager creates a new RemediationManager
+func (f ManagerFactory) NewRemediationManager(remediation *capm3.BareMetalRemediation,
+	capiMachine *capi.Machine, remediationLog logr.Logger,
+) (RemediationManagerInterface, error) {
+	remediationMgr, err := NewRemediationManager(f.client, remediation,
+		capiMachine, remediationLog,
+	)
+	if err != nil {
+		return nil, err
+	}
+	remediationMgr.RemoteClientGetter = f.machineConfig.RemoteClientGetter
+	return remediationMgr, nil
 }
@@ -34,7 +34,10 @@ var _ = Describe("Manager factory testing", func() {
 
 	BeforeEach(func() {
 		managerClient = fakeclient.NewFakeClientWithScheme(setupScheme())
-		managerFactory = NewManagerFactory(managerClient)
+		managerFactory = NewManagerFactory(managerClient, MachineManagerConfig{
+			AllowedHostNamespaces: []string{"inventory"},
+			QuarantineThreshold:   3,
+		})
 	})
 
 	It("returns a manager factory", func() {
@@ -42,10 +45,13 @@ var _ = Describe("Manager factory testing", func() {
 	})
 
 	It("returns a cluster manager", func() {
-		_, err := managerFactory.NewClusterManager(&capi.Cluster{},
+		clusterMgr, err := managerFactory.NewClusterManager(&capi.Cluster{},
 			&capm3.BareMetalCluster{}, clusterLog,
 		)
 		Expect(err).NotTo(HaveOccurred())
+		Expect(clusterMgr.(*ClusterManager).AllowedHostNamespaces).To(
+			Equal([]string{"inventory"}),
+		)
 	})
 
 	It("fails to return a cluster manager with nil cluster", func() {
@@ -63,10 +69,21 @@ var _ = Describe("Manager factory testing", func() {
 	})
 
 	It("returns a machine manager", func() {
-		_, err := managerFactory.NewMachineManager(&capi.Cluster{},
+		machineMgr, err := managerFactory.NewMachineManager(&capi.Cluster{},
 			&capm3.BareMetalCluster{}, &capi.Machine{}, &capm3.BareMetalMachine{},
 			clusterLog,
 		)
 		Expect(err).NotTo(HaveOccurred())
+		Expect(machineMgr.(*MachineManager).AllowedHostNamespaces).To(
+			Equal([]string{"inventory"}),
+		)
+		Expect(machineMgr.(*MachineManager).QuarantineThreshold).To(Equal(3))
+	})
+
+	It("returns a remediation manager", func() {
+		_, err := managerFactory.NewRemediationManager(
+			&capm3.BareMetalRemediation{}, &capi.Machine{}, clusterLog,
+		)
+		Expect(err).NotTo(HaveOccurred())
 	})
 })
@@ -0,0 +1,226 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/metal3-io/cluster-api-provider-baremetal/baremetal/remote"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// mirrorPodAnnotation is the annotation of the pods created by the kubelet
+	// from static manifests, which can not be evicted
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+	// defaultNodeDrainTimeout is the maximal duration of the drain when the
+	// policy has no timeout, so that a PodDisruptionBudget that can never be
+	// satisfied does not block the deprovisioning forever
+	defaultNodeDrainTimeout = 20 * time.Minute
+)
+
+// podNeedsEviction returns whether the pod must be evicted to drain its Node.
+// Completed pods, mirror pods and pods of DaemonSets are left on the Node.
+func podNeedsEviction(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+	controllerRef := metav1.GetControllerOf(pod)
+	return controllerRef == nil || controllerRef.Kind != "DaemonSet"
+}
+
+// drainNode cordons the Node of the host and evicts its pods, if enabled on
+// the BareMetalMachine, before the host is deprovisioned. A RequeueAfterError
+// is returned while pods are left on the Node. The drain completes when all
+// the pods are evicted, or when the timeout, defaultNodeDrainTimeout if not
+// set, expires.
+func (m *MachineManager) drainNode(ctx context.Context, host *bmh.BareMetalHost) error {
+	policy := m.BareMetalMachine.Spec.NodeDrain
+	if policy == nil {
+		return nil
+	}
+	status := m.BareMetalMachine.Status.NodeDrain
+	if status != nil && status.CompletedAt != nil {
+		return nil
+	}
+	// There is no workload cluster to drain when it is being deleted
+	if m.Cluster == nil || !m.Cluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	if status == nil {
+		status = &capm3.NodeDrainStatus{StartedAt: metav1.Now()}
+		m.BareMetalMachine.Status.NodeDrain = status
+	}
+
+	timeout := defaultNodeDrainTimeout
+	if policy.Timeout != nil {
+		timeout = policy.Timeout.Duration
+	}
+	if time.Since(status.StartedAt.Time) > timeout {
+		m.Log.Info("Node drain timed out, deprovisioning the host",
+			"host", host.Name, "timeout", timeout,
+		)
+		status.TimedOut = true
+		status.CompletedAt = &metav1.Time{Time: time.Now()}
+		return nil
+	}
+
+	corev1Remote, nodes, err := m.hostNodes(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	podsLeft := 0
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		status.Node = node.Name
+		left, err := m.evictNodePods(corev1Remote, node)
+		if err != nil {
+			return err
+		}
+		podsLeft += left
+	}
+	if podsLeft > 0 {
+		m.Log.Info("Draining node, requeuing", "node", status.Node,
+			"pods", podsLeft,
+		)
+		return &RequeueAfterError{RequeueAfter: requeueAfter}
+	}
+
+	m.Log.Info("Node drained", "node", status.Node)
+	status.CompletedAt = &metav1.Time{Time: time.Now()}
+	return nil
+}
+
+// uncordonNode makes the Node of the host schedulable again after it was
+// drained and the host provisioned again, for example for an image upgrade.
+func (m *MachineManager) uncordonNode(ctx context.Context, host *bmh.BareMetalHost) error {
+	if m.BareMetalMachine.Status.NodeDrain == nil {
+		return nil
+	}
+	if m.Cluster == nil || !m.Cluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	corev1Remote, nodes, err := m.hostNodes(ctx, host)
+	if err != nil {
+		return err
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if !node.Spec.Unschedulable {
+			continue
+		}
+		node.Spec.Unschedulable = false
+		if _, err := corev1Remote.Nodes().Update(node); err != nil {
+			return errors.Wrap(err, "unable to uncordon the target node")
+		}
+		m.Log.Info("Node uncordoned", "node", node.Name)
+	}
+	return nil
+}
+
+// hostNodes returns a client of the workload cluster and the Nodes running
+// on the host. A RequeueAfterError is returned if the cluster can not be
+// reached.
+func (m *MachineManager) hostNodes(ctx context.Context, host *bmh.BareMetalHost,
+) (clientcorev1.CoreV1Interface, *corev1.NodeList, error) {
+	clientGetter := m.RemoteClientGetter
+	if clientGetter == nil {
+		clientGetter = remote.NewClusterClient
+	}
+	corev1Remote, err := clientGetter(ctx, m.client, m.Cluster)
+	if err != nil {
+		m.Log.Info(fmt.Sprintf("error while creating a remote client: %v", err))
+		return nil, nil, &RequeueAfterError{RequeueAfter: requeueAfter}
+	}
+
+	nodes, err := corev1Remote.Nodes().List(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("metal3.io/uuid=%v", host.UID),
+	})
+	if err != nil {
+		m.Log.Info(fmt.Sprintf("error while accessing cluster: %v", err))
+		return nil, nil, &RequeueAfterError{RequeueAfter: requeueAfter}
+	}
+	return corev1Remote, nodes, nil
+}
+
+// evictNodePods cordons the node and requests the eviction of its pods. It
+// returns the number of pods left on the node. An eviction refused because
+// of a PodDisruptionBudget is retried on the next call.
+func (m *MachineManager) evictNodePods(corev1Remote clientcorev1.CoreV1Interface,
+	node *corev1.Node,
+) (int, error) {
+	if !node.Spec.Unschedulable {
+		node.Spec.Unschedulable = true
+		if _, err := corev1Remote.Nodes().Update(node); err != nil {
+			return 0, errors.Wrap(err, "unable to cordon the target node")
+		}
+		m.Log.Info("Node cordoned", "node", node.Name)
+	}
+
+	pods, err := corev1Remote.Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.Name),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to list the pods of the target node")
+	}
+
+	podsLeft := 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName != node.Name || !podNeedsEviction(pod) {
+			continue
+		}
+		podsLeft++
+		if pod.DeletionTimestamp != nil {
+			// Already evicted, waiting for the pod to terminate
+			continue
+		}
+		err := corev1Remote.Pods(pod.Namespace).Evict(&policyv1beta1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		})
+		switch {
+		case err == nil || apierrors.IsNotFound(err):
+		case apierrors.IsTooManyRequests(err):
+			m.Log.Info("Eviction refused by a PodDisruptionBudget, retrying",
+				"pod", pod.Name, "namespace", pod.Namespace,
+			)
+		default:
+			return 0, errors.Wrapf(err, "unable to evict pod %s/%s",
+				pod.Namespace, pod.Name,
+			)
+		}
+	}
+	return podsLeft, nil
+}
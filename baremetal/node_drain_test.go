@@ -0,0 +1,236 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientfake "k8s.io/client-go/kubernetes/fake"
+	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/klog/klogr"
+	"k8s.io/utils/pointer"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newDrainPod(name string, ownerKind string, annotations map[string]string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: annotations,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node-0",
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+		},
+	}
+	if ownerKind != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{
+			metav1.OwnerReference{
+				Kind:       ownerKind,
+				Name:       "owner",
+				Controller: pointer.BoolPtr(true),
+			},
+		}
+	}
+	return pod
+}
+
+var _ = Describe("Node drain", func() {
+
+	type testCaseDrainNode struct {
+		NodeDrain         *capm3.NodeDrainPolicy
+		Status            *capm3.NodeDrainStatus
+		NoNode            bool
+		Pods              []runtime.Object
+		PDBRefusal        bool
+		ExpectRequeue     bool
+		ExpectedEvictions []string
+		ExpectCompleted   bool
+		ExpectTimedOut    bool
+	}
+
+	DescribeTable("Test drainNode",
+		func(tc testCaseDrainNode) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "myhost",
+					Namespace: "myns",
+					UID:       "host-uid",
+				},
+			}
+			bmMachine := &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: "myns",
+				},
+				Spec: capm3.BareMetalMachineSpec{
+					NodeDrain: tc.NodeDrain,
+				},
+				Status: capm3.BareMetalMachineStatus{
+					NodeDrain: tc.Status,
+				},
+			}
+			objects := tc.Pods
+			if !tc.NoNode {
+				objects = append(objects, &corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "node-0",
+						Labels: map[string]string{"metal3.io/uuid": "host-uid"},
+					},
+				})
+			}
+			clientset := clientfake.NewSimpleClientset(objects...)
+			evictions := []string{}
+			clientset.PrependReactor("create", "pods",
+				func(action clienttesting.Action) (bool, runtime.Object, error) {
+					if action.GetSubresource() != "eviction" {
+						return false, nil, nil
+					}
+					eviction := action.(clienttesting.CreateAction).GetObject().(metav1.Object)
+					evictions = append(evictions, eviction.GetName())
+					if tc.PDBRefusal {
+						return true, nil, apierrors.NewTooManyRequests("PDB", 10)
+					}
+					return true, nil, nil
+				},
+			)
+
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm())
+			machineMgr, err := NewMachineManager(c, newCluster(clusterName), nil,
+				nil, bmMachine, klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			machineMgr.RemoteClientGetter = func(ctx context.Context, c client.Client,
+				cluster *capi.Cluster,
+			) (clientcorev1.CoreV1Interface, error) {
+				if tc.Status != nil && tc.Status.CompletedAt != nil {
+					return nil, errors.New("no remote client expected")
+				}
+				return clientset.CoreV1(), nil
+			}
+
+			err = machineMgr.drainNode(context.TODO(), host)
+			if tc.ExpectRequeue {
+				_, ok := errors.Cause(err).(HasRequeueAfterError)
+				Expect(ok).To(BeTrue())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(evictions).To(ConsistOf(tc.ExpectedEvictions))
+
+			status := bmMachine.Status.NodeDrain
+			if tc.NodeDrain == nil {
+				Expect(status).To(BeNil())
+				return
+			}
+			Expect(status).NotTo(BeNil())
+			Expect(status.CompletedAt != nil).To(Equal(tc.ExpectCompleted))
+			Expect(status.TimedOut).To(Equal(tc.ExpectTimedOut))
+
+			if !tc.NoNode && !tc.ExpectTimedOut && tc.Status == nil {
+				Expect(status.Node).To(Equal("node-0"))
+				node, err := clientset.CoreV1().Nodes().Get("node-0", metav1.GetOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(node.Spec.Unschedulable).To(BeTrue())
+			}
+		},
+		Entry("Drain disabled", testCaseDrainNode{}),
+		Entry("Pods evicted", testCaseDrainNode{
+			NodeDrain: &capm3.NodeDrainPolicy{},
+			Pods: []runtime.Object{
+				newDrainPod("pod-0", "ReplicaSet", nil),
+				newDrainPod("pod-1", "", nil),
+				newDrainPod("daemon", "DaemonSet", nil),
+				newDrainPod("static", "", map[string]string{mirrorPodAnnotation: ""}),
+			},
+			ExpectRequeue:     true,
+			ExpectedEvictions: []string{"pod-0", "pod-1"},
+		}),
+		Entry("Eviction refused by a PodDisruptionBudget", testCaseDrainNode{
+			NodeDrain: &capm3.NodeDrainPolicy{},
+			Pods: []runtime.Object{
+				newDrainPod("pod-0", "ReplicaSet", nil),
+			},
+			PDBRefusal:        true,
+			ExpectRequeue:     true,
+			ExpectedEvictions: []string{"pod-0"},
+		}),
+		Entry("Only pods left on the node", testCaseDrainNode{
+			NodeDrain: &capm3.NodeDrainPolicy{},
+			Pods: []runtime.Object{
+				newDrainPod("daemon", "DaemonSet", nil),
+			},
+			ExpectCompleted: true,
+		}),
+		Entry("No node", testCaseDrainNode{
+			NodeDrain:       &capm3.NodeDrainPolicy{},
+			NoNode:          true,
+			ExpectCompleted: true,
+		}),
+		Entry("Timed out", testCaseDrainNode{
+			NodeDrain: &capm3.NodeDrainPolicy{
+				Timeout: &metav1.Duration{Duration: time.Minute},
+			},
+			Status: &capm3.NodeDrainStatus{
+				StartedAt: metav1.Time{Time: time.Now().Add(-time.Hour)},
+			},
+			Pods: []runtime.Object{
+				newDrainPod("pod-0", "ReplicaSet", nil),
+			},
+			ExpectCompleted: true,
+			ExpectTimedOut:  true,
+		}),
+		Entry("Timed out with the default timeout", testCaseDrainNode{
+			NodeDrain: &capm3.NodeDrainPolicy{},
+			Status: &capm3.NodeDrainStatus{
+				StartedAt: metav1.Time{Time: time.Now().Add(-time.Hour)},
+			},
+			Pods: []runtime.Object{
+				newDrainPod("pod-0", "ReplicaSet", nil),
+			},
+			PDBRefusal:      true,
+			ExpectCompleted: true,
+			ExpectTimedOut:  true,
+		}),
+		Entry("Already completed", testCaseDrainNode{
+			NodeDrain: &capm3.NodeDrainPolicy{},
+			Status: &capm3.NodeDrainStatus{
+				StartedAt:   metav1.Now(),
+				CompletedAt: &metav1.Time{Time: time.Now()},
+			},
+			ExpectCompleted: true,
+		}),
+	)
+})
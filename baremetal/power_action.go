@@ -0,0 +1,220 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HostPowerOffAnnotation is the annotation set on a BareMetalHost to
+	// keep it powered off while it is consumed by a BareMetalMachine, for
+	// example while it is rebooted.
+	HostPowerOffAnnotation = "metal3.io/power-off"
+	// RebootRequestAnnotation is the annotation set on a BareMetalMachine
+	// to reboot its BareMetalHost. It is removed once the host is powered
+	// on again.
+	RebootRequestAnnotation = "metal3.io/reboot-request"
+	// PowerOffRequestAnnotation is the annotation set on a BareMetalMachine
+	// to power off its BareMetalHost. The host is powered on again when the
+	// annotation is removed.
+	PowerOffRequestAnnotation = "metal3.io/power-off-request"
+	// ForcePowerOffAnnotation is the annotation set on a BareMetalMachine
+	// of the control plane, next to the PowerOffRequestAnnotation, to allow
+	// powering off its BareMetalHost.
+	ForcePowerOffAnnotation = "metal3.io/force-power-off"
+)
+
+// requestedPowerAction returns the power action requested in the
+// annotations of the BareMetalMachine, the power off taking precedence.
+func (m *MachineManager) requestedPowerAction() capm3.PowerAction {
+	annotations := m.BareMetalMachine.Annotations
+	if _, ok := annotations[PowerOffRequestAnnotation]; ok {
+		return capm3.PowerActionPowerOff
+	}
+	if _, ok := annotations[RebootRequestAnnotation]; ok {
+		return capm3.PowerActionReboot
+	}
+	return ""
+}
+
+// handlePowerAction applies the power action requested on the
+// BareMetalMachine to the host, through the HostPowerOffAnnotation, and
+// records its progress in the status. The host is not updated. A reboot in
+// progress is completed even if the request is removed. A reboot requested
+// before the host was provisioned is dropped, the host having just booted.
+func (m *MachineManager) handlePowerAction(host *bmh.BareMetalHost) {
+	action := m.requestedPowerAction()
+	status := m.BareMetalMachine.Status.PowerAction
+
+	// A pending power off is handled as a new request
+	if status != nil && status.Pending != "" {
+		if status.Action == capm3.PowerActionReboot {
+			m.Log.Info("Host booted by its provisioning, dropping the reboot request",
+				"host", host.Name,
+			)
+			delete(m.BareMetalMachine.Annotations, RebootRequestAnnotation)
+			m.BareMetalMachine.Status.PowerAction = nil
+			return
+		}
+		status = nil
+		m.BareMetalMachine.Status.PowerAction = nil
+	}
+
+	if status != nil && status.Action == capm3.PowerActionReboot &&
+		status.CompletedAt == nil {
+		m.rebootHost(host, status)
+		return
+	}
+
+	switch action {
+	case capm3.PowerActionPowerOff:
+		if m.isControlPlane() {
+			if _, ok := m.BareMetalMachine.Annotations[ForcePowerOffAnnotation]; !ok {
+				if status == nil || status.Refused == "" {
+					m.Log.Info("Refusing to power off a control plane host",
+						"host", host.Name,
+					)
+					m.BareMetalMachine.Status.PowerAction = &capm3.PowerActionStatus{
+						Action:      capm3.PowerActionPowerOff,
+						RequestedAt: metav1.Now(),
+						Refused: "the BareMetalMachine is part of the control plane, set the " +
+							ForcePowerOffAnnotation + " annotation to power it off",
+					}
+				}
+				return
+			}
+		}
+		if status == nil || status.Action != capm3.PowerActionPowerOff ||
+			status.Refused != "" {
+			m.Log.Info("Powering off host", "host", host.Name)
+			status = &capm3.PowerActionStatus{
+				Action:      capm3.PowerActionPowerOff,
+				RequestedAt: metav1.Now(),
+			}
+			m.BareMetalMachine.Status.PowerAction = status
+		}
+		setHostPowerOffAnnotation(host, true)
+		if status.CompletedAt == nil && !host.Status.PoweredOn {
+			m.Log.Info("Host powered off", "host", host.Name)
+			now := metav1.Now()
+			status.PoweredOffAt = &now
+			status.CompletedAt = &now
+		}
+
+	case capm3.PowerActionReboot:
+		m.Log.Info("Rebooting host", "host", host.Name)
+		status = &capm3.PowerActionStatus{
+			Action:      capm3.PowerActionReboot,
+			RequestedAt: metav1.Now(),
+		}
+		m.BareMetalMachine.Status.PowerAction = status
+		m.rebootHost(host, status)
+
+	default:
+		// Power the host on again once the power off request is removed
+		if status != nil && status.Action == capm3.PowerActionPowerOff {
+			if status.Refused == "" {
+				m.Log.Info("Powering on host", "host", host.Name)
+				setHostPowerOffAnnotation(host, false)
+			}
+			m.BareMetalMachine.Status.PowerAction = nil
+		}
+	}
+}
+
+// recordPendingPowerAction records in the status the power action requested
+// on the BareMetalMachine while its host is not provisioned. A power off is
+// applied once the host is provisioned, while a reboot is dropped since the
+// provisioning boots the host.
+func (m *MachineManager) recordPendingPowerAction(host *bmh.BareMetalHost) {
+	action := m.requestedPowerAction()
+	status := m.BareMetalMachine.Status.PowerAction
+
+	if action == "" {
+		// The request was removed before it was applied
+		if status != nil && status.Pending != "" {
+			m.BareMetalMachine.Status.PowerAction = nil
+		}
+		return
+	}
+	if status != nil && status.Action == action && status.Pending != "" {
+		return
+	}
+	m.Log.Info("Power action requested before the host is provisioned",
+		"host", host.Name, "action", action,
+	)
+	pending := "the BareMetalHost " + host.Name +
+		" is not provisioned yet, the action is applied once it is"
+	if action == capm3.PowerActionReboot {
+		pending = "the BareMetalHost " + host.Name +
+			" is not provisioned yet, the reboot is dropped since the" +
+			" provisioning boots it"
+	}
+	m.BareMetalMachine.Status.PowerAction = &capm3.PowerActionStatus{
+		Action:      action,
+		RequestedAt: metav1.Now(),
+		Pending:     pending,
+	}
+}
+
+// rebootHost powers the host off, then on again, and removes the reboot
+// request from the BareMetalMachine once done.
+func (m *MachineManager) rebootHost(host *bmh.BareMetalHost,
+	status *capm3.PowerActionStatus,
+) {
+	if status.PoweredOffAt == nil {
+		if host.Status.PoweredOn {
+			setHostPowerOffAnnotation(host, true)
+			return
+		}
+		now := metav1.Now()
+		status.PoweredOffAt = &now
+	}
+
+	setHostPowerOffAnnotation(host, false)
+	if !host.Status.PoweredOn {
+		return
+	}
+	m.Log.Info("Host rebooted", "host", host.Name)
+	now := metav1.Now()
+	status.CompletedAt = &now
+	delete(m.BareMetalMachine.Annotations, RebootRequestAnnotation)
+}
+
+// hostKeptPoweredOff returns whether the host is kept powered off, by a power
+// action of its BareMetalMachine or by a BareMetalRemediation.
+func hostKeptPoweredOff(host *bmh.BareMetalHost) bool {
+	_, powerOff := host.Annotations[HostPowerOffAnnotation]
+	_, remediation := host.Annotations[RemediationPowerOffAnnotation]
+	return powerOff || remediation
+}
+
+// setHostPowerOffAnnotation sets or removes the HostPowerOffAnnotation on the
+// host.
+func setHostPowerOffAnnotation(host *bmh.BareMetalHost, powerOff bool) {
+	if !powerOff {
+		delete(host.Annotations, HostPowerOffAnnotation)
+		return
+	}
+	if host.Annotations == nil {
+		host.Annotations = make(map[string]string)
+	}
+	host.Annotations[HostPowerOffAnnotation] = ""
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/klogr"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Power actions", func() {
+
+	type testCaseHandlePowerAction struct {
+		Annotations   map[string]string
+		ControlPlane  bool
+		Status        *capm3.PowerActionStatus
+		HostPoweredOn bool
+		HostPowerOff  bool
+
+		ExpectedAction      capm3.PowerAction
+		ExpectHostPowerOff  bool
+		ExpectPoweredOff    bool
+		ExpectCompleted     bool
+		ExpectRefused       bool
+		ExpectRebootRequest bool
+	}
+
+	DescribeTable("Test handlePowerAction",
+		func(tc testCaseHandlePowerAction) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "myhost",
+					Namespace: "myns",
+				},
+				Status: bmh.BareMetalHostStatus{
+					PoweredOn: tc.HostPoweredOn,
+				},
+			}
+			if tc.HostPowerOff {
+				host.Annotations = map[string]string{HostPowerOffAnnotation: ""}
+			}
+			bmMachine := &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "mybmmachine",
+					Namespace:   "myns",
+					Annotations: tc.Annotations,
+				},
+				Status: capm3.BareMetalMachineStatus{
+					PowerAction: tc.Status,
+				},
+			}
+			machine := &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mymachine",
+					Namespace: "myns",
+				},
+			}
+			if tc.ControlPlane {
+				machine.Labels = map[string]string{
+					capi.MachineControlPlaneLabelName: "true",
+				}
+			}
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm())
+			machineMgr, err := NewMachineManager(c, nil, nil, machine, bmMachine,
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			machineMgr.handlePowerAction(host)
+
+			_, powerOff := host.Annotations[HostPowerOffAnnotation]
+			Expect(powerOff).To(Equal(tc.ExpectHostPowerOff))
+			_, rebootRequested := bmMachine.Annotations[RebootRequestAnnotation]
+			Expect(rebootRequested).To(Equal(tc.ExpectRebootRequest))
+
+			status := bmMachine.Status.PowerAction
+			if tc.ExpectedAction == "" {
+				Expect(status).To(BeNil())
+				return
+			}
+			Expect(status).NotTo(BeNil())
+			Expect(status.Action).To(Equal(tc.ExpectedAction))
+			Expect(status.PoweredOffAt != nil).To(Equal(tc.ExpectPoweredOff))
+			Expect(status.CompletedAt != nil).To(Equal(tc.ExpectCompleted))
+			Expect(status.Refused != "").To(Equal(tc.ExpectRefused))
+			Expect(status.Pending).To(BeEmpty())
+		},
+		Entry("No request", testCaseHandlePowerAction{
+			HostPoweredOn: true,
+		}),
+		Entry("Reboot requested", testCaseHandlePowerAction{
+			Annotations:         map[string]string{RebootRequestAnnotation: ""},
+			HostPoweredOn:       true,
+			ExpectedAction:      capm3.PowerActionReboot,
+			ExpectHostPowerOff:  true,
+			ExpectRebootRequest: true,
+		}),
+		Entry("Reboot, host powered off", testCaseHandlePowerAction{
+			Annotations: map[string]string{RebootRequestAnnotation: ""},
+			Status: &capm3.PowerActionStatus{
+				Action:      capm3.PowerActionReboot,
+				RequestedAt: metav1.Now(),
+			},
+			HostPowerOff:        true,
+			ExpectedAction:      capm3.PowerActionReboot,
+			ExpectPoweredOff:    true,
+			ExpectRebootRequest: true,
+		}),
+		Entry("Reboot, host powered on again", testCaseHandlePowerAction{
+			Annotations: map[string]string{RebootRequestAnnotation: ""},
+			Status: &capm3.PowerActionStatus{
+				Action:       capm3.PowerActionReboot,
+				RequestedAt:  metav1.Now(),
+				PoweredOffAt: &metav1.Time{},
+			},
+			HostPoweredOn:    true,
+			ExpectedAction:   capm3.PowerActionReboot,
+			ExpectPoweredOff: true,
+			ExpectCompleted:  true,
+		}),
+		Entry("Reboot completed even if the request is removed",
+			testCaseHandlePowerAction{
+				Status: &capm3.PowerActionStatus{
+					Action:      capm3.PowerActionReboot,
+					RequestedAt: metav1.Now(),
+				},
+				HostPowerOff:     true,
+				ExpectedAction:   capm3.PowerActionReboot,
+				ExpectPoweredOff: true,
+			},
+		),
+		Entry("Power off requested", testCaseHandlePowerAction{
+			Annotations:        map[string]string{PowerOffRequestAnnotation: ""},
+			HostPoweredOn:      true,
+			ExpectedAction:     capm3.PowerActionPowerOff,
+			ExpectHostPowerOff: true,
+		}),
+		Entry("Power off, host powered off", testCaseHandlePowerAction{
+			Annotations: map[string]string{PowerOffRequestAnnotation: ""},
+			Status: &capm3.PowerActionStatus{
+				Action:      capm3.PowerActionPowerOff,
+				RequestedAt: metav1.Now(),
+			},
+			HostPowerOff:       true,
+			ExpectedAction:     capm3.PowerActionPowerOff,
+			ExpectHostPowerOff: true,
+			ExpectPoweredOff:   true,
+			ExpectCompleted:    true,
+		}),
+		Entry("Power off refused on the control plane", testCaseHandlePowerAction{
+			Annotations:    map[string]string{PowerOffRequestAnnotation: ""},
+			ControlPlane:   true,
+			HostPoweredOn:  true,
+			ExpectedAction: capm3.PowerActionPowerOff,
+			ExpectRefused:  true,
+		}),
+		Entry("Power off forced on the control plane", testCaseHandlePowerAction{
+			Annotations: map[string]string{
+				PowerOffRequestAnnotation: "",
+				ForcePowerOffAnnotation:   "",
+			},
+			ControlPlane: true,
+			Status: &capm3.PowerActionStatus{
+				Action:      capm3.PowerActionPowerOff,
+				RequestedAt: metav1.Now(),
+				Refused:     "refused",
+			},
+			HostPoweredOn:      true,
+			ExpectedAction:     capm3.PowerActionPowerOff,
+			ExpectHostPowerOff: true,
+		}),
+		Entry("Pending power off applied", testCaseHandlePowerAction{
+			Annotations: map[string]string{PowerOffRequestAnnotation: ""},
+			Status: &capm3.PowerActionStatus{
+				Action:      capm3.PowerActionPowerOff,
+				RequestedAt: metav1.Now(),
+				Pending:     "pending",
+			},
+			HostPoweredOn:      true,
+			ExpectedAction:     capm3.PowerActionPowerOff,
+			ExpectHostPowerOff: true,
+		}),
+		Entry("Pending reboot dropped", testCaseHandlePowerAction{
+			Annotations: map[string]string{RebootRequestAnnotation: ""},
+			Status: &capm3.PowerActionStatus{
+				Action:      capm3.PowerActionReboot,
+				RequestedAt: metav1.Now(),
+				Pending:     "pending",
+			},
+			HostPoweredOn: true,
+		}),
+		Entry("Power off request removed", testCaseHandlePowerAction{
+			Status: &capm3.PowerActionStatus{
+				Action:      capm3.PowerActionPowerOff,
+				RequestedAt: metav1.Now(),
+				CompletedAt: &metav1.Time{},
+			},
+			HostPowerOff: true,
+		}),
+	)
+
+	type testCaseRecordPendingPowerAction struct {
+		Annotations map[string]string
+		Status      *capm3.PowerActionStatus
+
+		ExpectedAction capm3.PowerAction
+	}
+
+	DescribeTable("Test recordPendingPowerAction",
+		func(tc testCaseRecordPendingPowerAction) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "myhost",
+					Namespace: "myns",
+				},
+			}
+			bmMachine := &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "mybmmachine",
+					Namespace:   "myns",
+					Annotations: tc.Annotations,
+				},
+				Status: capm3.BareMetalMachineStatus{
+					PowerAction: tc.Status,
+				},
+			}
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm())
+			machineMgr, err := NewMachineManager(c, nil, nil, nil, bmMachine,
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			machineMgr.recordPendingPowerAction(host)
+
+			status := bmMachine.Status.PowerAction
+			if tc.ExpectedAction == "" {
+				Expect(status).To(BeNil())
+				return
+			}
+			Expect(status).NotTo(BeNil())
+			Expect(status.Action).To(Equal(tc.ExpectedAction))
+			Expect(status.Pending).NotTo(BeEmpty())
+			Expect(status.PoweredOffAt).To(BeNil())
+			Expect(status.CompletedAt).To(BeNil())
+		},
+		Entry("No request", testCaseRecordPendingPowerAction{}),
+		Entry("Reboot requested", testCaseRecordPendingPowerAction{
+			Annotations:    map[string]string{RebootRequestAnnotation: ""},
+			ExpectedAction: capm3.PowerActionReboot,
+		}),
+		Entry("Power off requested", testCaseRecordPendingPowerAction{
+			Annotations:    map[string]string{PowerOffRequestAnnotation: ""},
+			ExpectedAction: capm3.PowerActionPowerOff,
+		}),
+		Entry("Power off requested after a completed reboot",
+			testCaseRecordPendingPowerAction{
+				Annotations: map[string]string{PowerOffRequestAnnotation: ""},
+				Status: &capm3.PowerActionStatus{
+					Action:      capm3.PowerActionReboot,
+					RequestedAt: metav1.Now(),
+					CompletedAt: &metav1.Time{},
+				},
+				ExpectedAction: capm3.PowerActionPowerOff,
+			},
+		),
+		Entry("Request removed before it was applied",
+			testCaseRecordPendingPowerAction{
+				Status: &capm3.PowerActionStatus{
+					Action:      capm3.PowerActionPowerOff,
+					RequestedAt: metav1.Now(),
+					Pending:     "pending",
+				},
+			},
+		),
+	)
+})
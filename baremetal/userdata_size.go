@@ -0,0 +1,113 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// UserDataTooLargeError is returned when the user data of a BareMetalHost is
+// over the size limit, even after compression if enabled. The user data or
+// the limit must be changed, retrying does not help.
+type UserDataTooLargeError struct {
+	Size       int
+	Limit      int
+	Compressed bool
+}
+
+// Error implements the error interface
+func (e *UserDataTooLargeError) Error() string {
+	if e.Compressed {
+		return fmt.Sprintf("user data is %d bytes after compression, over the limit of %d bytes",
+			e.Size, e.Limit,
+		)
+	}
+	return fmt.Sprintf("user data is %d bytes, over the limit of %d bytes, set compressUserData to compress it",
+		e.Size, e.Limit,
+	)
+}
+
+// InvalidUserDataError is returned when the bootstrap data of the Machine can
+// not be used with the configuration of the BareMetalMachine, e.g. when
+// compressing Ignition bootstrap data. The configuration must be changed,
+// retrying does not help.
+type InvalidUserDataError struct {
+	Reason string
+}
+
+// Error implements the error interface
+func (e *InvalidUserDataError) Error() string {
+	return e.Reason
+}
+
+// isInvalidUserDataError returns whether the error is caused by the user data
+// or the configuration of the BareMetalMachine, and can not be fixed by
+// retrying.
+func isInvalidUserDataError(err error) bool {
+	switch err.(type) {
+	case *UserDataTooLargeError, *InvalidUserDataError:
+		return true
+	}
+	return false
+}
+
+// compressUserData gzips the user data. The gzip header has no name nor
+// modification time, so that the result only changes with the user data.
+func compressUserData(data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&compressed, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// limitUserData compresses the user data if enabled on the BareMetalMachine,
+// and returns an UserDataTooLargeError if the result is over the size limit.
+func (m *MachineManager) limitUserData(data []byte, format BootstrapFormat) ([]byte, error) {
+	compress := m.BareMetalMachine.Spec.CompressUserData
+	if compress {
+		if format == BootstrapFormatIgnition {
+			return nil, &InvalidUserDataError{
+				Reason: "Ignition bootstrap data can not be compressed, unset compressUserData",
+			}
+		}
+		var err error
+		data, err = compressUserData(data)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compress the user data")
+		}
+	}
+	if m.UserDataSizeLimit > 0 && len(data) > m.UserDataSizeLimit {
+		return nil, &UserDataTooLargeError{
+			Size:       len(data),
+			Limit:      m.UserDataSizeLimit,
+			Compressed: compress,
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"k8s.io/klog/klogr"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("User data size", func() {
+
+	userData := []byte("#cloud-config\n" + strings.Repeat("runcmd: []\n", 100))
+
+	type testCaseLimitUserData struct {
+		Limit         int
+		Compress      bool
+		Format        BootstrapFormat
+		ExpectedError string
+	}
+
+	DescribeTable("Test limitUserData",
+		func(tc testCaseLimitUserData) {
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm())
+			machineMgr, err := NewMachineManager(c, nil, nil, nil,
+				&capm3.BareMetalMachine{
+					Spec: capm3.BareMetalMachineSpec{
+						CompressUserData: tc.Compress,
+					},
+				},
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			machineMgr.UserDataSizeLimit = tc.Limit
+
+			data, err := machineMgr.limitUserData(userData, tc.Format)
+			if tc.ExpectedError != "" {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(tc.ExpectedError))
+				// Retrying does not help
+				Expect(isInvalidUserDataError(err)).To(BeTrue())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			if !tc.Compress {
+				Expect(data).To(Equal(userData))
+				return
+			}
+			reader, err := gzip.NewReader(bytes.NewReader(data))
+			Expect(err).NotTo(HaveOccurred())
+			decompressed, err := ioutil.ReadAll(reader)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decompressed).To(Equal(userData))
+		},
+		Entry("No limit", testCaseLimitUserData{}),
+		Entry("Under the limit", testCaseLimitUserData{
+			Limit: 2000,
+		}),
+		Entry("Over the limit", testCaseLimitUserData{
+			Limit:         1000,
+			ExpectedError: "user data is 1114 bytes, over the limit of 1000 bytes, set compressUserData to compress it",
+		}),
+		Entry("Under the limit after compression", testCaseLimitUserData{
+			Limit:    1000,
+			Compress: true,
+		}),
+		Entry("Over the limit after compression", testCaseLimitUserData{
+			Limit:         10,
+			Compress:      true,
+			ExpectedError: "bytes after compression, over the limit of 10 bytes",
+		}),
+		Entry("Ignition", testCaseLimitUserData{
+			Compress:      true,
+			Format:        BootstrapFormatIgnition,
+			ExpectedError: "Ignition bootstrap data can not be compressed",
+		}),
+	)
+})
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"bytes"
+	"context"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// provisioningStarted returns whether the host started provisioning, and so
+// already got its user data
+func provisioningStarted(host *bmh.BareMetalHost) bool {
+	switch host.Status.Provisioning.State {
+	case bmh.StateProvisioning, bmh.StateProvisioningError,
+		bmh.StateProvisioned, bmh.StateExternallyProvisioned,
+		bmh.StateDeprovisioning:
+		return true
+	}
+	return false
+}
+
+// syncUserData keeps the user data secret created for the host in sync with
+// the bootstrap data of the Machine. The secret is refreshed until the host
+// starts provisioning. After that, a change is only flagged in the status of
+// the BareMetalMachine.
+func (m *MachineManager) syncUserData(ctx context.Context, host *bmh.BareMetalHost) error {
+	userDataRef := m.BareMetalMachine.Spec.UserData
+	if userDataRef == nil || userDataRef.Name != m.BareMetalMachine.Name+"-user-data" {
+		return nil
+	}
+	if m.Machine.Spec.Bootstrap.DataSecretName == nil &&
+		m.Machine.Spec.Bootstrap.Data == nil {
+		return nil
+	}
+
+	userData, format, err := m.userData(ctx)
+	if err != nil {
+		return err
+	}
+
+	secret := corev1.Secret{}
+	key := client.ObjectKey{
+		Name:      userDataRef.Name,
+		Namespace: userDataRef.Namespace,
+	}
+	err = m.client.Get(ctx, key, &secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && bytes.Equal(secret.Data["userData"], userData) {
+		m.BareMetalMachine.Status.UserDataDrifted = false
+		return nil
+	}
+
+	if !provisioningStarted(host) {
+		m.Log.Info("Refreshing the user data secret", "secret", userDataRef.Name)
+		m.BareMetalMachine.Status.UserDataDrifted = false
+		return m.writeUserData(ctx, host, userData, format)
+	}
+	if !m.BareMetalMachine.Status.UserDataDrifted {
+		m.Log.Info("User data changed after the host started provisioning",
+			"host", host.Name,
+		)
+	}
+	m.BareMetalMachine.Status.UserDataDrifted = true
+	return nil
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baremetal
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/klogr"
+	"k8s.io/utils/pointer"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("User data sync", func() {
+
+	type testCaseSyncUserData struct {
+		HostState      bmh.ProvisioningState
+		UserDataName   string
+		CopiedUserData string
+		Drifted        bool
+		// Synced by GetBaremetalHostID while waiting for the host
+		WaitingForHost   bool
+		ExpectedUserData string
+		ExpectedDrifted  bool
+	}
+
+	DescribeTable("Test syncUserData",
+		func(tc testCaseSyncUserData) {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "myhost",
+					Namespace: "myns",
+				},
+				Status: bmh.BareMetalHostStatus{
+					Provisioning: bmh.ProvisionStatus{
+						State: tc.HostState,
+					},
+				},
+			}
+			machine := &capi.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mymachine",
+					Namespace: "myns",
+				},
+				Spec: capi.MachineSpec{
+					Bootstrap: capi.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap"),
+					},
+				},
+			}
+			bmMachine := &capm3.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mybmmachine",
+					Namespace: "myns",
+					Annotations: map[string]string{
+						HostAnnotation: "myns/myhost",
+					},
+				},
+				Spec: capm3.BareMetalMachineSpec{
+					UserData: &corev1.SecretReference{
+						Name:      tc.UserDataName,
+						Namespace: "myns",
+					},
+				},
+				Status: capm3.BareMetalMachineStatus{
+					UserDataDrifted: tc.Drifted,
+				},
+			}
+			bootstrapSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "bootstrap",
+					Namespace: "myns",
+				},
+				Data: map[string][]byte{
+					"value": []byte("#cloud-config\nnew\n"),
+				},
+			}
+			copiedSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tc.UserDataName,
+					Namespace: "myns",
+				},
+				Data: map[string][]byte{
+					"userData": []byte(tc.CopiedUserData),
+				},
+			}
+			c := fakeclient.NewFakeClientWithScheme(setupSchemeMm(),
+				bootstrapSecret, copiedSecret, host,
+			)
+			machineMgr, err := NewMachineManager(c, nil, nil, machine, bmMachine,
+				klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			if tc.WaitingForHost {
+				_, err = machineMgr.GetBaremetalHostID(context.TODO())
+				_, ok := errors.Cause(err).(HasRequeueAfterError)
+				Expect(ok).To(BeTrue())
+			} else {
+				err = machineMgr.syncUserData(context.TODO(), host)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			savedSecret := corev1.Secret{}
+			err = c.Get(context.TODO(),
+				client.ObjectKey{Name: tc.UserDataName, Namespace: "myns"},
+				&savedSecret,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(savedSecret.Data["userData"])).To(
+				Equal(tc.ExpectedUserData),
+			)
+			Expect(bmMachine.Status.UserDataDrifted).To(Equal(tc.ExpectedDrifted))
+		},
+		Entry("Unchanged", testCaseSyncUserData{
+			HostState:        bmh.StateProvisioned,
+			UserDataName:     "mybmmachine-user-data",
+			CopiedUserData:   "#cloud-config\nnew\n",
+			Drifted:          true,
+			ExpectedUserData: "#cloud-config\nnew\n",
+		}),
+		Entry("Changed before provisioning", testCaseSyncUserData{
+			HostState:        bmh.StateReady,
+			UserDataName:     "mybmmachine-user-data",
+			CopiedUserData:   "#cloud-config\nold\n",
+			ExpectedUserData: "#cloud-config\nnew\n",
+		}),
+		Entry("Changed while waiting for the host", testCaseSyncUserData{
+			HostState:        bmh.StateReady,
+			UserDataName:     "mybmmachine-user-data",
+			CopiedUserData:   "#cloud-config\nold\n",
+			WaitingForHost:   true,
+			ExpectedUserData: "#cloud-config\nnew\n",
+		}),
+		Entry("Changed after provisioning", testCaseSyncUserData{
+			HostState:        bmh.StateProvisioning,
+			UserDataName:     "mybmmachine-user-data",
+			CopiedUserData:   "#cloud-config\nold\n",
+			ExpectedUserData: "#cloud-config\nold\n",
+			ExpectedDrifted:  true,
+		}),
+		Entry("Secret not generated", testCaseSyncUserData{
+			HostState:        bmh.StateReady,
+			UserDataName:     "other-user-data",
+			CopiedUserData:   "#cloud-config\nold\n",
+			ExpectedUserData: "#cloud-config\nold\n",
+		}),
+	)
+})
@@ -23,9 +23,11 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
 	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
 	"github.com/metal3-io/cluster-api-provider-baremetal/baremetal"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/pointer"
 	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
 	capierrors "sigs.k8s.io/cluster-api/errors"
@@ -48,11 +50,16 @@ type BareMetalClusterReconciler struct {
 	Client         client.Client
 	ManagerFactory baremetal.ManagerFactoryInterface
 	Log            logr.Logger
+	// AllowedHostNamespaces is the list of namespaces whose hosts may be
+	// claimed by the BareMetalMachines of the other namespaces
+	AllowedHostNamespaces []string
 }
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=baremetalclusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=baremetalclusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch
+// +kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=baremetalmachinetemplates,verbs=get;list;watch
 
 // Reconcile reads that state of the cluster for a BareMetalCluster object and makes changes based on the state read
 // and what is in the BareMetalCluster.Spec
@@ -139,6 +146,11 @@ func reconcileNormal(ctx context.Context, clusterMgr baremetal.ClusterManagerInt
 		return ctrl.Result{}, errors.Wrap(err, "failed to get ip for the API endpoint")
 	}
 
+	// Publish the failure domains found on the BareMetalHosts
+	if err := clusterMgr.UpdateFailureDomains(ctx); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update the failure domains")
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -178,5 +190,47 @@ func (r *BareMetalClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				),
 			},
 		).
+		Watches(
+			&source.Kind{Type: &bmh.BareMetalHost{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.BareMetalHostToBareMetalClusters),
+			},
+		).
 		Complete(r)
 }
+
+// BareMetalHostToBareMetalClusters will return a reconcile request for each
+// BareMetalCluster that has a FailureDomainLabel and whose machines may
+// claim the host, so that its failure domains are updated. Hosts of the
+// allowed host namespaces map to the BareMetalClusters of all namespaces.
+func (r *BareMetalClusterReconciler) BareMetalHostToBareMetalClusters(obj handler.MapObject) []ctrl.Request {
+	requests := []ctrl.Request{}
+	if _, ok := obj.Object.(*bmh.BareMetalHost); !ok {
+		return requests
+	}
+
+	listOptions := []client.ListOption{}
+	if !util.Contains(r.AllowedHostNamespaces, obj.Meta.GetNamespace()) {
+		listOptions = append(listOptions,
+			client.InNamespace(obj.Meta.GetNamespace()),
+		)
+	}
+	clusters := capm3.BareMetalClusterList{}
+	err := r.Client.List(context.Background(), &clusters, listOptions...)
+	if err != nil {
+		r.Log.Error(err, "failed to list BareMetalClusters")
+		return requests
+	}
+	for _, cluster := range clusters.Items {
+		if cluster.Spec.FailureDomainLabel == "" {
+			continue
+		}
+		requests = append(requests, ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      cluster.Name,
+				Namespace: cluster.Namespace,
+			},
+		})
+	}
+	return requests
+}
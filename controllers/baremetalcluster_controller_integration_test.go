@@ -51,7 +51,7 @@ var _ = Describe("Reconcile Baremetalcluster", func() {
 
 			r := &BareMetalClusterReconciler{
 				Client:         c,
-				ManagerFactory: baremetal.NewManagerFactory(c),
+				ManagerFactory: baremetal.NewManagerFactory(c, baremetal.MachineManagerConfig{}),
 				Log:            klogr.New(),
 			}
 
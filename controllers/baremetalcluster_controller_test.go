@@ -27,10 +27,11 @@ import (
 var _ = Describe("BareMetalCluster controller", func() {
 
 	type testCaseClusterNormal struct {
-		CreateError   bool
-		UpdateError   bool
-		ExpectError   bool
-		ExpectRequeue bool
+		CreateError         bool
+		UpdateError         bool
+		FailureDomainsError bool
+		ExpectError         bool
+		ExpectRequeue       bool
 	}
 
 	type testCaseClusterDelete struct {
@@ -61,11 +62,19 @@ var _ = Describe("BareMetalCluster controller", func() {
 			if tc.CreateError {
 				returnedError = errors.New("Error")
 				m.EXPECT().UpdateClusterStatus().MaxTimes(0)
+				m.EXPECT().UpdateFailureDomains(context.TODO()).MaxTimes(0)
 			} else {
 				if tc.UpdateError {
 					returnedError = errors.New("Error")
+					m.EXPECT().UpdateFailureDomains(context.TODO()).MaxTimes(0)
 				} else {
 					returnedError = nil
+					if tc.FailureDomainsError {
+						m.EXPECT().UpdateFailureDomains(context.TODO()).
+							Return(errors.New("Error"))
+					} else {
+						m.EXPECT().UpdateFailureDomains(context.TODO()).Return(nil)
+					}
 				}
 				m.EXPECT().UpdateClusterStatus().Return(returnedError)
 				returnedError = nil
@@ -104,6 +113,13 @@ var _ = Describe("BareMetalCluster controller", func() {
 			ExpectError:   true,
 			ExpectRequeue: false,
 		}),
+		Entry("Failure domains error", testCaseClusterNormal{
+			CreateError:         false,
+			UpdateError:         false,
+			FailureDomainsError: true,
+			ExpectError:         true,
+			ExpectRequeue:       false,
+		}),
 	)
 
 	DescribeTable("Test ClusterReconcileDelete",
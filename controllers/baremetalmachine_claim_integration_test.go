@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bmh "github.com/metal3-io/baremetal-operator/pkg/apis/metal3/v1alpha1"
+	infrav1 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/metal3-io/cluster-api-provider-baremetal/baremetal"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/klogr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Concurrent host claiming", func() {
+	const (
+		claimNamespace = "claim-test"
+		nbHosts        = 3
+		nbMachines     = 10
+	)
+
+	It("Should give each host to a single BareMetalMachine", func() {
+		ctx := context.Background()
+
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: claimNamespace},
+		})).To(Succeed())
+
+		for i := 0; i < nbHosts; i++ {
+			host := &bmh.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("host-%d", i),
+					Namespace: claimNamespace,
+				},
+				Spec: bmh.BareMetalHostSpec{
+					BMC: bmh.BMCDetails{
+						Address:         fmt.Sprintf("ipmi://192.168.111.%d", i+1),
+						CredentialsName: fmt.Sprintf("host-%d-bmc-secret", i),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, host)).To(Succeed())
+		}
+
+		machineMgrs := []*baremetal.MachineManager{}
+		for i := 0; i < nbMachines; i++ {
+			machine := &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("machine-%d", i),
+					Namespace: claimNamespace,
+				},
+				Spec: clusterv1.MachineSpec{
+					ClusterName: clusterName,
+				},
+			}
+			bmMachine := &infrav1.BareMetalMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("bmmachine-%d", i),
+					Namespace: claimNamespace,
+				},
+				Spec: infrav1.BareMetalMachineSpec{
+					Image: infrav1.Image{
+						URL:      "http://172.22.0.1/images/image.qcow2",
+						Checksum: "http://172.22.0.1/images/image.qcow2.md5sum",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, bmMachine)).To(Succeed())
+			bmMachine.TypeMeta = metav1.TypeMeta{
+				Kind:       "BareMetalMachine",
+				APIVersion: infrav1.GroupVersion.String(),
+			}
+
+			machineMgr, err := baremetal.NewMachineManager(k8sClient, nil, nil,
+				machine, bmMachine, klogr.New(),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			machineMgrs = append(machineMgrs, machineMgr)
+		}
+
+		By("Associating all the machines concurrently")
+		var wg sync.WaitGroup
+		for _, machineMgr := range machineMgrs {
+			wg.Add(1)
+			go func(machineMgr *baremetal.MachineManager) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				err := machineMgr.Associate(ctx)
+				if err != nil {
+					_, ok := err.(*baremetal.RequeueAfterError)
+					Expect(ok).To(BeTrue(), "unexpected error: %v", err)
+				}
+			}(machineMgr)
+		}
+		wg.Wait()
+
+		By("Checking that each host has a single consumer")
+		hosts := bmh.BareMetalHostList{}
+		Expect(k8sClient.List(ctx, &hosts,
+			client.InNamespace(claimNamespace),
+		)).To(Succeed())
+		Expect(hosts.Items).To(HaveLen(nbHosts))
+		consumers := map[string]string{}
+		for _, host := range hosts.Items {
+			Expect(host.Spec.ConsumerRef).NotTo(BeNil())
+			Expect(host.Labels[clusterv1.ClusterLabelName]).To(Equal(clusterName))
+			Expect(consumers).NotTo(HaveKey(host.Spec.ConsumerRef.Name))
+			consumers[host.Spec.ConsumerRef.Name] = host.Name
+		}
+
+		By("Checking that only the consumers are associated with a host")
+		associated := 0
+		for _, machineMgr := range machineMgrs {
+			bmMachine := infrav1.BareMetalMachine{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{
+				Name:      machineMgr.BareMetalMachine.Name,
+				Namespace: claimNamespace,
+			}, &bmMachine)).To(Succeed())
+			hostKey, ok := bmMachine.Annotations[baremetal.HostAnnotation]
+			if !ok {
+				Expect(consumers).NotTo(HaveKey(bmMachine.Name))
+				continue
+			}
+			associated++
+			Expect(hostKey).To(Equal(
+				claimNamespace + "/" + consumers[bmMachine.Name],
+			))
+		}
+		Expect(associated).To(Equal(nbHosts))
+	})
+})
@@ -25,6 +25,7 @@ import (
 	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
 	"github.com/metal3-io/cluster-api-provider-baremetal/baremetal"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/pointer"
@@ -169,7 +170,7 @@ func (r *BareMetalMachineReconciler) reconcileNormal(ctx context.Context,
 	// if the machine is already provisioned, return
 	if machineMgr.IsProvisioned() {
 		err := machineMgr.Update(ctx)
-		return ctrl.Result{}, err
+		return checkError(err, "failed to update the BareMetalMachine")
 	}
 
 	// Make sure bootstrap data is available and populated. If not, return, we
@@ -203,7 +204,7 @@ func (r *BareMetalMachineReconciler) reconcileNormal(ctx context.Context,
 	}
 
 	err = machineMgr.Update(ctx)
-	return ctrl.Result{}, err
+	return checkError(err, "failed to update the BareMetalMachine")
 }
 
 func (r *BareMetalMachineReconciler) reconcileDelete(ctx context.Context,
@@ -244,6 +245,12 @@ func (r *BareMetalMachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				ToRequests: handler.ToRequestsFunc(r.BareMetalHostToBareMetalMachines),
 			},
 		).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.BootstrapSecretToBareMetalMachines),
+			},
+		).
 		Complete(r)
 }
 
@@ -304,6 +311,70 @@ func (r *BareMetalMachineReconciler) BareMetalHostToBareMetalMachines(obj handle
 	return []ctrl.Request{}
 }
 
+// BootstrapSecretToBareMetalMachines will return a reconcile request for the
+// BareMetalMachines of the Machines using the Secret as bootstrap data, and
+// for the BareMetalMachines using it as extra user data, so that the user
+// data of their BareMetalHosts is refreshed.
+func (r *BareMetalMachineReconciler) BootstrapSecretToBareMetalMachines(obj handler.MapObject) []ctrl.Request {
+	result := []ctrl.Request{}
+	secret, ok := obj.Object.(*corev1.Secret)
+	if !ok {
+		r.Log.Error(errors.Errorf("expected a Secret but got a %T", obj.Object), "failed to get BareMetalMachine for Secret")
+		return nil
+	}
+	log := r.Log.WithValues("Secret", secret.Name, "Namespace", secret.Namespace)
+
+	capiMachineList := &capi.MachineList{}
+	if err := r.Client.List(context.TODO(), capiMachineList, client.InNamespace(secret.Namespace)); err != nil {
+		log.Error(err, "failed to list Machines")
+		return nil
+	}
+	gk := capm3.GroupVersion.WithKind("BareMetalMachine").GroupKind()
+	for _, m := range capiMachineList.Items {
+		if m.Spec.Bootstrap.DataSecretName == nil ||
+			*m.Spec.Bootstrap.DataSecretName != secret.Name {
+			continue
+		}
+		if m.Spec.InfrastructureRef.GroupVersionKind().GroupKind() != gk ||
+			m.Spec.InfrastructureRef.Name == "" {
+			continue
+		}
+		name := client.ObjectKey{Namespace: m.Namespace, Name: m.Spec.InfrastructureRef.Name}
+		result = append(result, ctrl.Request{NamespacedName: name})
+	}
+
+	bmMachineList := &capm3.BareMetalMachineList{}
+	if err := r.Client.List(context.TODO(), bmMachineList, client.InNamespace(secret.Namespace)); err != nil {
+		log.Error(err, "failed to list BareMetalMachines")
+		return nil
+	}
+	for _, bmm := range bmMachineList.Items {
+		for _, ref := range bmm.Spec.ExtraUserData {
+			if ref.Name != secret.Name {
+				continue
+			}
+			name := client.ObjectKey{Namespace: bmm.Namespace, Name: bmm.Name}
+			if !containsRequest(result, name) {
+				result = append(result, ctrl.Request{NamespacedName: name})
+			}
+			break
+		}
+	}
+
+	return result
+}
+
+// containsRequest returns whether the list of requests contains a request
+// for the object.
+func containsRequest(requests []ctrl.Request, name client.ObjectKey) bool {
+	for _, request := range requests {
+		if request.NamespacedName == name {
+			return true
+		}
+	}
+	return false
+}
+
 // setError sets the ErrorMessage and ErrorReason fields on the baremetalmachine
 func setErrorBMMachine(bmm *capm3.BareMetalMachine, message string, reason capierrors.MachineStatusError) {
 
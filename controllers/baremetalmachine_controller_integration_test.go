@@ -171,6 +171,7 @@ var _ = Describe("Reconcile BaremetalMachine", func() {
 		CheckBootStrapReady     bool
 		CheckBMHostCleaned      bool
 		CheckBMHostProvisioned  bool
+		ExpectedHostFailures    string
 	}
 
 	DescribeTable("Reconcile tests",
@@ -189,7 +190,7 @@ var _ = Describe("Reconcile BaremetalMachine", func() {
 
 			r := &BareMetalMachineReconciler{
 				Client:           c,
-				ManagerFactory:   baremetal.NewManagerFactory(c),
+				ManagerFactory:   baremetal.NewManagerFactory(c, baremetal.MachineManagerConfig{}),
 				Log:              klogr.New(),
 				CapiClientGetter: mockCapiClientGetter,
 			}
@@ -253,6 +254,9 @@ var _ = Describe("Reconcile BaremetalMachine", func() {
 				Expect(testBMHost.Spec.UserData).NotTo(BeNil())
 				Expect(testBMHost.Spec.ConsumerRef.Name).To(Equal(testBMmachine.Name))
 			}
+			Expect(testBMHost.Annotations[baremetal.HostProvisioningFailuresAnnotation]).To(
+				Equal(tc.ExpectedHostFailures),
+			)
 			if tc.ClusterInfraReady {
 				Expect(testcluster.Status.InfrastructureReady).To(BeTrue())
 			} else {
@@ -507,6 +511,37 @@ var _ = Describe("Reconcile BaremetalMachine", func() {
 				CheckBootStrapReady:     true,
 			},
 		),
+		//Given: Machine(with Bootstrap data), BMMachine (Annotation Given), BMH (provisioning failed)
+		//Expected: No Error, Requeue expected
+		//		The provisioning failure is counted on the BMH
+		Entry("Should count the provisioning failure of the BMH and requeue",
+			TestCaseReconcile{
+				Objects: []runtime.Object{
+					newBareMetalMachine(bareMetalMachineName, bmmMetaWithAnnotation(), &infrav1.BareMetalMachineSpec{
+						Image: infrav1.Image{
+							Checksum: "abcd",
+							URL:      "abcd",
+						},
+					}, nil, false),
+					machineWithBootstrap(),
+					newCluster(clusterName, nil, nil),
+					newBareMetalCluster(baremetalClusterName, nil, nil, nil, false),
+					newBareMetalHost(nil, &bmh.BareMetalHostStatus{
+						ErrorType: bmh.ProvisioningError,
+						Provisioning: bmh.ProvisionStatus{
+							State: bmh.StateProvisioning,
+						},
+					}),
+				},
+				ErrorExpected:           false,
+				RequeueExpected:         true,
+				ExpectedRequeueDuration: requeueAfter,
+				ClusterInfraReady:       true,
+				CheckBMFinalizer:        true,
+				CheckBootStrapReady:     true,
+				ExpectedHostFailures:    "1",
+			},
+		),
 		//Given: Baremetalmachine with annotation to a BMH provisioned, machine with
 		// bootstrap data, no target cluster node available
 		//Expected: no error, requeing. ProviderID should not be set.
@@ -181,7 +181,7 @@ var _ = Describe("BareMetalMachine manager", func() {
 
 			bmReconcile = &BareMetalMachineReconciler{
 				Client:           c,
-				ManagerFactory:   baremetal.NewManagerFactory(c),
+				ManagerFactory:   baremetal.NewManagerFactory(c, baremetal.MachineManagerConfig{}),
 				Log:              klogr.New(),
 				CapiClientGetter: nil,
 			}
@@ -264,7 +264,7 @@ var _ = Describe("BareMetalMachine manager", func() {
 
 			bmReconcile = &BareMetalMachineReconciler{
 				Client:           c,
-				ManagerFactory:   baremetal.NewManagerFactory(c),
+				ManagerFactory:   baremetal.NewManagerFactory(c, baremetal.MachineManagerConfig{}),
 				Log:              klogr.New(),
 				CapiClientGetter: nil,
 			}
@@ -377,6 +377,69 @@ var _ = Describe("BareMetalMachine manager", func() {
 		Expect(len(out)).To(Equal(0), "Expected 0 request, found %d", len(out))
 	})
 
+	It("TestBareMetalMachineReconciler_BootstrapSecretToBareMetalMachines", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-bootstrap-data",
+				Namespace: namespaceName,
+			},
+		}
+		machine0 := newMachine(clusterName, "my-machine-0", "my-baremetal-machine-0")
+		machine0.Spec.Bootstrap.DataSecretName = pointer.StringPtr("my-bootstrap-data")
+		machine1 := newMachine(clusterName, "my-machine-1", "my-baremetal-machine-1")
+		machine1.Spec.Bootstrap.DataSecretName = pointer.StringPtr("other-bootstrap-data")
+		// Intentionally omitted
+		machine2 := newMachine(clusterName, "my-machine-2", "")
+		machine2.Spec.Bootstrap.DataSecretName = pointer.StringPtr("my-bootstrap-data")
+		objects := []runtime.Object{machine0, machine1, machine2}
+		c := fake.NewFakeClientWithScheme(setupScheme(), objects...)
+		r := BareMetalMachineReconciler{
+			Client: c,
+			Log:    klogr.New(),
+		}
+		mo := handler.MapObject{
+			Object: secret,
+		}
+		out := r.BootstrapSecretToBareMetalMachines(mo)
+		Expect(len(out)).To(Equal(1), "Expected 1 baremetal machine to reconcile but got %d", len(out))
+		Expect(out[0].Name).To(Equal("my-baremetal-machine-0"))
+		Expect(out[0].Namespace).To(Equal(namespaceName))
+	})
+
+	It("TestBareMetalMachineReconciler_BootstrapSecretToBareMetalMachines_ExtraUserData", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ssh-keys",
+				Namespace: namespaceName,
+			},
+		}
+		extraUserData := func(name string) []corev1.SecretKeySelector {
+			return []corev1.SecretKeySelector{
+				corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: name},
+					Key:                  "cloud-config",
+				},
+			}
+		}
+		bmMachine0 := newBareMetalMachine("my-baremetal-machine-0", nil, nil, nil, false)
+		bmMachine0.Spec.ExtraUserData = extraUserData("ssh-keys")
+		bmMachine1 := newBareMetalMachine("my-baremetal-machine-1", nil, nil, nil, false)
+		bmMachine1.Spec.ExtraUserData = extraUserData("ntp")
+		objects := []runtime.Object{bmMachine0, bmMachine1}
+		c := fake.NewFakeClientWithScheme(setupScheme(), objects...)
+		r := BareMetalMachineReconciler{
+			Client: c,
+			Log:    klogr.New(),
+		}
+		mo := handler.MapObject{
+			Object: secret,
+		}
+		out := r.BootstrapSecretToBareMetalMachines(mo)
+		Expect(len(out)).To(Equal(1), "Expected 1 baremetal machine to reconcile but got %d", len(out))
+		Expect(out[0].Name).To(Equal("my-baremetal-machine-0"))
+		Expect(out[0].Namespace).To(Equal(namespaceName))
+	})
+
 	type TestCaseBMHToBMM struct {
 		Host          *bmh.BareMetalHost
 		ExpectRequest bool
@@ -0,0 +1,154 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	capm3 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/metal3-io/cluster-api-provider-baremetal/baremetal"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	capi "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	remediationControllerName = "BareMetalRemediation-controller"
+)
+
+// BareMetalRemediationReconciler reconciles a BareMetalRemediation object
+type BareMetalRemediationReconciler struct {
+	Client         client.Client
+	ManagerFactory baremetal.ManagerFactoryInterface
+	Log            logr.Logger
+}
+
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=baremetalremediations,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=baremetalremediations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts,verbs=get;list;watch;update;patch
+
+// Reconcile handles BareMetalRemediation events
+func (r *BareMetalRemediationReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, rerr error) {
+	ctx := context.Background()
+	remediationLog := r.Log.WithName(remediationControllerName).WithValues("baremetal-remediation", req.NamespacedName)
+
+	// Fetch the BareMetalRemediation instance.
+	remediation := &capm3.BareMetalRemediation{}
+
+	if err := r.Client.Get(ctx, req.NamespacedName, remediation); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	helper, err := patch.NewHelper(remediation, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to init patch helper")
+	}
+	// Always patch the remediation exiting this function so we can persist any BareMetalRemediation changes.
+	defer func() {
+		err := helper.Patch(ctx, remediation)
+		if err != nil {
+			remediationLog.Info("failed to Patch BareMetalRemediation")
+		}
+	}()
+
+	// Fetch the Machine of the same name.
+	capiMachine := &capi.Machine{}
+	if err := r.Client.Get(ctx, req.NamespacedName, capiMachine); err != nil {
+		if apierrors.IsNotFound(err) {
+			remediationLog.Info("Machine to remediate not found")
+			// The host was released with the BareMetalMachine of the
+			// Machine, there is nothing to power on.
+			remediation.Finalizers = util.Filter(remediation.Finalizers,
+				capm3.RemediationFinalizer,
+			)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Return early if the Cluster is paused.
+	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, capiMachine.ObjectMeta)
+	if err == nil && util.IsPaused(cluster, remediation) {
+		remediationLog.Info("reconciliation is paused for this object")
+		return ctrl.Result{Requeue: true, RequeueAfter: requeueAfter}, nil
+	}
+
+	// Create a helper for managing the remediation of the machine.
+	remediationMgr, err := r.ManagerFactory.NewRemediationManager(remediation,
+		capiMachine, remediationLog,
+	)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to create helper for managing the remediationMgr")
+	}
+
+	// Handle deleted remediations
+	if !remediation.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, remediationMgr)
+	}
+
+	// Handle non-deleted remediations
+	return r.reconcileNormal(ctx, remediationMgr)
+}
+
+func (r *BareMetalRemediationReconciler) reconcileNormal(ctx context.Context,
+	remediationMgr baremetal.RemediationManagerInterface,
+) (ctrl.Result, error) {
+	// If the BareMetalRemediation doesn't have finalizer, add it.
+	remediationMgr.SetFinalizer()
+
+	// Nothing left to do once the remediation is finished
+	if remediationMgr.IsFinished() {
+		return ctrl.Result{}, nil
+	}
+
+	if err := remediationMgr.Remediate(ctx); err != nil {
+		return checkError(err, "failed to remediate the Machine")
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *BareMetalRemediationReconciler) reconcileDelete(ctx context.Context,
+	remediationMgr baremetal.RemediationManagerInterface,
+) (ctrl.Result, error) {
+
+	// power the host on again if it is being power cycled
+	if err := remediationMgr.Delete(ctx); err != nil {
+		return checkError(err, "failed to delete BareMetalRemediation")
+	}
+
+	// BareMetalRemediation is marked for deletion and ready to be deleted,
+	// so remove the finalizer.
+	remediationMgr.UnsetFinalizer()
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager will add watches for this controller
+func (r *BareMetalRemediationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&capm3.BareMetalRemediation{}).
+		Complete(r)
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/golang/mock/gomock"
+	infrav1 "github.com/metal3-io/cluster-api-provider-baremetal/api/v1alpha3"
+	"github.com/metal3-io/cluster-api-provider-baremetal/baremetal"
+	baremetal_mocks "github.com/metal3-io/cluster-api-provider-baremetal/baremetal/mocks"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/klogr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type reconcileRemediationTestCase struct {
+	ExpectError    bool
+	ExpectRequeue  bool
+	Finished       bool
+	RemediateFails bool
+	RemediateWaits bool
+}
+
+func setReconcileRemediationExpectations(ctrl *gomock.Controller,
+	tc reconcileRemediationTestCase,
+) *baremetal_mocks.MockRemediationManagerInterface {
+
+	m := baremetal_mocks.NewMockRemediationManagerInterface(ctrl)
+
+	m.EXPECT().SetFinalizer()
+	m.EXPECT().IsFinished().Return(tc.Finished)
+	if tc.Finished {
+		m.EXPECT().Remediate(context.TODO()).MaxTimes(0)
+		return m
+	}
+
+	if tc.RemediateFails {
+		m.EXPECT().Remediate(context.TODO()).Return(errors.New("failed"))
+	} else if tc.RemediateWaits {
+		m.EXPECT().Remediate(context.TODO()).Return(&baremetal.RequeueAfterError{})
+	} else {
+		m.EXPECT().Remediate(context.TODO()).Return(nil)
+	}
+	return m
+}
+
+var _ = Describe("BareMetalRemediation manager", func() {
+
+	Describe("Test RemediationReconcileNormal", func() {
+
+		var gomockCtrl *gomock.Controller
+		var bmReconcile *BareMetalRemediationReconciler
+
+		BeforeEach(func() {
+			gomockCtrl = gomock.NewController(GinkgoT())
+
+			c := fake.NewFakeClientWithScheme(setupScheme())
+
+			bmReconcile = &BareMetalRemediationReconciler{
+				Client:         c,
+				ManagerFactory: baremetal.NewManagerFactory(c, baremetal.MachineManagerConfig{}),
+				Log:            klogr.New(),
+			}
+		})
+
+		AfterEach(func() {
+			gomockCtrl.Finish()
+		})
+
+		DescribeTable("Remediation tests",
+			func(tc reconcileRemediationTestCase) {
+				m := setReconcileRemediationExpectations(gomockCtrl, tc)
+				res, err := bmReconcile.reconcileNormal(context.TODO(), m)
+
+				if tc.ExpectError {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).NotTo(HaveOccurred())
+				}
+				if tc.ExpectRequeue {
+					Expect(res.Requeue).To(BeTrue())
+				} else {
+					Expect(res.Requeue).To(BeFalse())
+				}
+			},
+			Entry("Remediation finished", reconcileRemediationTestCase{
+				Finished: true,
+			}),
+			Entry("Remediation success", reconcileRemediationTestCase{}),
+			Entry("Remediation failure", reconcileRemediationTestCase{
+				ExpectError:    true,
+				RemediateFails: true,
+			}),
+			Entry("Remediation requeue", reconcileRemediationTestCase{
+				ExpectRequeue:  true,
+				RemediateWaits: true,
+			}),
+		)
+	})
+
+	Describe("Test RemediationReconcileDelete", func() {
+
+		var gomockCtrl *gomock.Controller
+		var bmReconcile *BareMetalRemediationReconciler
+
+		BeforeEach(func() {
+			gomockCtrl = gomock.NewController(GinkgoT())
+
+			c := fake.NewFakeClientWithScheme(setupScheme())
+
+			bmReconcile = &BareMetalRemediationReconciler{
+				Client:         c,
+				ManagerFactory: baremetal.NewManagerFactory(c, baremetal.MachineManagerConfig{}),
+				Log:            klogr.New(),
+			}
+		})
+
+		AfterEach(func() {
+			gomockCtrl.Finish()
+		})
+
+		It("powers the host on and removes the finalizer", func() {
+			m := baremetal_mocks.NewMockRemediationManagerInterface(gomockCtrl)
+			m.EXPECT().Delete(context.TODO()).Return(nil)
+			m.EXPECT().UnsetFinalizer()
+
+			res, err := bmReconcile.reconcileDelete(context.TODO(), m)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Requeue).To(BeFalse())
+		})
+
+		It("keeps the finalizer when the host can not be powered on", func() {
+			m := baremetal_mocks.NewMockRemediationManagerInterface(gomockCtrl)
+			m.EXPECT().Delete(context.TODO()).Return(errors.New("failed"))
+			m.EXPECT().UnsetFinalizer().MaxTimes(0)
+
+			_, err := bmReconcile.reconcileDelete(context.TODO(), m)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	It("removes the finalizer when the Machine to remediate does not exist", func() {
+		now := metav1.Now()
+		remediation := &infrav1.BareMetalRemediation{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "mymachine",
+				Namespace:         "myns",
+				DeletionTimestamp: &now,
+				Finalizers:        []string{infrav1.RemediationFinalizer},
+			},
+		}
+		c := fake.NewFakeClientWithScheme(setupScheme(), remediation)
+		bmReconcile := &BareMetalRemediationReconciler{
+			Client:         c,
+			ManagerFactory: baremetal.NewManagerFactory(c, baremetal.MachineManagerConfig{}),
+			Log:            klogr.New(),
+		}
+
+		_, err := bmReconcile.Reconcile(ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      "mymachine",
+				Namespace: "myns",
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		savedRemediation := &infrav1.BareMetalRemediation{}
+		err = c.Get(context.TODO(), types.NamespacedName{
+			Name:      "mymachine",
+			Namespace: "myns",
+		}, savedRemediation)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(savedRemediation.Finalizers).To(BeEmpty())
+	})
+
+	It("does nothing when the Machine to remediate does not exist", func() {
+		remediation := &infrav1.BareMetalRemediation{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "mymachine",
+				Namespace: "myns",
+			},
+		}
+		c := fake.NewFakeClientWithScheme(setupScheme(), remediation)
+		bmReconcile := &BareMetalRemediationReconciler{
+			Client:         c,
+			ManagerFactory: baremetal.NewManagerFactory(c, baremetal.MachineManagerConfig{}),
+			Log:            klogr.New(),
+		}
+
+		res, err := bmReconcile.Reconcile(ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      "mymachine",
+				Namespace: "myns",
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Requeue).To(BeFalse())
+	})
+})
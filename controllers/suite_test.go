@@ -100,7 +100,12 @@ var _ = BeforeSuite(func(done Done) {
 
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths: []string{filepath.Join("..", "config", "crd", "bases")},
+		// The BareMetalHost CRD of the baremetal-operator version we depend
+		// on is kept in config/crd/bmo.
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "config", "crd", "bases"),
+			filepath.Join("..", "config", "crd", "bmo"),
+		},
 	}
 
 	var err error
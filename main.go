@@ -20,6 +20,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	bmoapis "github.com/metal3-io/baremetal-operator/pkg/apis"
@@ -52,6 +53,10 @@ var (
 	webhookPort             int
 	healthAddr              string
 	watchNamespace          string
+	allowedHostNamespaces   string
+	quarantineThreshold     int
+	userDataSizeLimit       int
+	deprovisionTimeout      time.Duration
 )
 
 func init() {
@@ -76,6 +81,14 @@ func main() {
 		"Webhook Server port (set to 0 to disable)")
 	flag.StringVar(&healthAddr, "health-addr", ":9440",
 		"The address the health endpoint binds to.")
+	flag.StringVar(&allowedHostNamespaces, "allowed-host-namespaces", "",
+		"Comma-separated list of namespaces in which BareMetalMachines of any other namespace are allowed to claim BareMetalHosts.")
+	flag.IntVar(&quarantineThreshold, "host-quarantine-threshold", 0,
+		"Number of provisioning failures after which a BareMetalHost is quarantined (set to 0 to disable)")
+	flag.IntVar(&userDataSizeLimit, "user-data-size-limit", 0,
+		"Maximal size in bytes of the user data of a BareMetalHost, after compression (set to 0 to disable)")
+	flag.DurationVar(&deprovisionTimeout, "deprovision-timeout", 0,
+		"Time after which a BareMetalHost that is still not deprovisioned sets its BareMetalMachine in error (set to 0 to disable)")
 	flag.Parse()
 
 	ctrl.SetLogger(klogr.New())
@@ -156,9 +169,23 @@ func setupReconcilers(mgr ctrl.Manager) {
 	if webhookPort != 0 {
 		return
 	}
+	machineConfig := baremetal.MachineManagerConfig{
+		QuarantineThreshold: quarantineThreshold,
+		UserDataSizeLimit:   userDataSizeLimit,
+		RemoteClientGetter:  capm3remote.NewClusterClient,
+		DeprovisionTimeout:  deprovisionTimeout,
+	}
+	for _, namespace := range strings.Split(allowedHostNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			machineConfig.AllowedHostNamespaces = append(
+				machineConfig.AllowedHostNamespaces, namespace,
+			)
+		}
+	}
+
 	if err := (&controllers.BareMetalMachineReconciler{
 		Client:           mgr.GetClient(),
-		ManagerFactory:   baremetal.NewManagerFactory(mgr.GetClient()),
+		ManagerFactory:   baremetal.NewManagerFactory(mgr.GetClient(), machineConfig),
 		Log:              ctrl.Log.WithName("controllers").WithName("BareMetalMachine"),
 		CapiClientGetter: capm3remote.NewClusterClient,
 	}).SetupWithManager(mgr); err != nil {
@@ -168,12 +195,23 @@ func setupReconcilers(mgr ctrl.Manager) {
 
 	if err := (&controllers.BareMetalClusterReconciler{
 		Client:         mgr.GetClient(),
-		ManagerFactory: baremetal.NewManagerFactory(mgr.GetClient()),
+		ManagerFactory: baremetal.NewManagerFactory(mgr.GetClient(), machineConfig),
 		Log:            ctrl.Log.WithName("controllers").WithName("BareMetalCluster"),
+
+		AllowedHostNamespaces: machineConfig.AllowedHostNamespaces,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BareMetalClusterReconciler")
 		os.Exit(1)
 	}
+
+	if err := (&controllers.BareMetalRemediationReconciler{
+		Client:         mgr.GetClient(),
+		ManagerFactory: baremetal.NewManagerFactory(mgr.GetClient(), machineConfig),
+		Log:            ctrl.Log.WithName("controllers").WithName("BareMetalRemediation"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BareMetalRemediationReconciler")
+		os.Exit(1)
+	}
 }
 
 func setupWebhooks(mgr ctrl.Manager) {